@@ -4,31 +4,301 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type Symbol string
 
+// Namespace returns the part of a namespaced symbol like str/join before the '/', or "" for symbols without namespace
+func (s Symbol) Namespace() string {
+	if i := strings.IndexByte(string(s), '/'); i > 0 && i < len(s)-1 {
+		return string(s[:i])
+	}
+	return ""
+}
+
+// Name returns the part of a namespaced symbol like str/join after the '/', or the symbol itself if it has no namespace
+func (s Symbol) Name() string {
+	if ns := s.Namespace(); ns != "" {
+		return string(s[len(ns)+1:])
+	}
+	return string(s)
+}
+
+// Namespace holds functions, special forms and variables under a common name, so that libraries do not collide.
+// A Namespace bound to a name in the env or the lexical scope, e.g. "str", makes its entries available as str/join etc.
+type Namespace map[string]interface{}
+
+// Keyword is read from :name, and evaluates to itself. The Keyword does not include the colon
+type Keyword string
+
+// Vector is read from [...] literals. Unlike lists, which are function calls, vectors evaluate to vectors of their evaluated elements
+type Vector []interface{}
+
+// Map is read from {key value ...} literals, and evaluates to a map of its evaluated keys and values.
+// Keys are compared like = does, and entries are kept in the order they were added
+type Map []MapEntry
+
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Get returns the value stored under key
+func (m Map) Get(key interface{}) (interface{}, bool) {
+	return m.get(key, nil)
+}
+
+func (m Map) get(key interface{}, visiting map[[2]visit]bool) (interface{}, bool) {
+	for _, entry := range m {
+		if equalVisiting(entry.Key, key, visiting) {
+			return entry.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ReaderOptions configure ReadWithOptions
+type ReaderOptions struct {
+	// File is the name of the source text, used in error messages
+	File string
+	// SourceMap, if not nil, records the spans of all lists and their elements
+	SourceMap *SourceMap
+	// Readtable, if not nil, adds the reader macros registered in it to the syntax
+	Readtable *Readtable
+	// The following limits protect against input that would take too much time or memory to read, or overflow the
+	// stack. Exceeding a limit is a ParseError of kind LimitExceeded. Limits that are 0 are not enforced
+
+	// MaxDepth limits how deeply lists, vectors, maps and prefixed forms like 'x can be nested
+	MaxDepth int
+	// MaxForms limits the number of forms read, counting nested forms
+	MaxForms int
+	// MaxStringLength limits the length of string literals in bytes
+	MaxStringLength int
+	// MaxInputBytes limits the length of the input
+	MaxInputBytes int
+	// NormalizeSymbol, if not nil, is applied to the names of symbols and keywords, e.g. norm.NFC.String
+	// of golang.org/x/text/unicode/norm, so that differently composed characters read as the same symbol
+	NormalizeSymbol func(string) string
+}
+
+type reader struct {
+	s     string
+	opts  ReaderOptions
+	base  Position // the position of s[0]
+	src   *source
+	depth int  // the number of lists, vectors, maps and prefixed forms being read
+	forms int  // the number of forms read so far
+	cst   bool // set by ReadCST, which reads forms discarded using #_ as nodes, without calling reader macros
+
+	// set by ReadAllRecovering
+	recovering bool
+	errs       []*ParseError
+	closers    []byte // the closing characters of the lists, vectors and maps being read
+}
+
 func Read(sexpStr string, startIdx int) (sexp interface{}, idx int, err error) {
+	r := newReader(sexpStr, ReaderOptions{}, startPosition)
+	return r.read(startIdx)
+}
+
+// ReadWithOptions is like Read, but errors are prefixed with "file:line:col" and followed by a caret snippet of the source
+func ReadWithOptions(sexpStr string, startIdx int, opts ReaderOptions) (sexp interface{}, idx int, err error) {
+	r := newReader(sexpStr, opts, startPosition)
+	sexp, idx, err = r.read(startIdx)
+	if err != nil {
+		err = r.annotate(err, idx)
+	}
+	return sexp, idx, err
+}
+
+func newReader(s string, opts ReaderOptions, base Position) *reader {
+	r := &reader{s: s, opts: opts, base: base}
+	if opts.SourceMap != nil {
+		r.src = opts.SourceMap.source(opts.File, s, base)
+	}
+	return r
+}
+
+func (r *reader) read(startIdx int) (sexp interface{}, idx int, err error) {
 	defer func() {
-		if r := recover(); r != nil {
+		if rec := recover(); rec != nil {
 			sexp = nil
 			var ok bool
-			err, ok = r.(error)
+			err, ok = rec.(error)
 			if !ok {
-				err = fmt.Errorf("minsexp: %v", r)
+				err = fmt.Errorf("minsexp: %v", rec)
 			}
 			err = errors.WithStack(err)
 		}
 	}()
-	return parseSexp(sexpStr, startIdx)
+	if err := r.checkInputSize(); err != nil {
+		return nil, startIdx, r.locate(err)
+	}
+	sexp, idx, err = r.parseSexp(startIdx)
+	return sexp, idx, r.locate(err)
+}
+
+// readNext reads the form following startIdx, if there is one
+func (r *reader) readNext(startIdx int) (sexp interface{}, idx int, found bool, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			sexp = nil
+			var ok bool
+			err, ok = rec.(error)
+			if !ok {
+				err = fmt.Errorf("minsexp: %v", rec)
+			}
+			err = errors.WithStack(err)
+		}
+	}()
+	if err := r.checkInputSize(); err != nil {
+		return nil, startIdx, false, r.locate(err)
+	}
+	idx, err = r.getNextNonWSP(startIdx)
+	if err != nil {
+		_, idx, err = r.skip(err, idx, idx)
+	}
+	if err != nil || idx == len(r.s) {
+		return nil, idx, false, r.locate(err)
+	}
+	sexp, idx, err = r.parseSexp(idx)
+	return sexp, idx, true, r.locate(err)
+}
+
+func (r *reader) checkInputSize() error {
+	if max := r.opts.MaxInputBytes; max > 0 && r.base.Offset+len(r.s) > max {
+		return parseError(LimitExceeded, max-r.base.Offset, fmt.Sprintf("input longer than MaxInputBytes (%d)", max))
+	}
+	return nil
+}
+
+// enter is called before reading a list, vector, map or prefixed form starting at startIdx, and leave after it
+func (r *reader) enter(startIdx int) error {
+	r.depth++
+	if max := r.opts.MaxDepth; max > 0 && r.depth > max {
+		return parseError(LimitExceeded, startIdx, fmt.Sprintf("forms nested deeper than MaxDepth (%d)", max))
+	}
+	return nil
+}
+
+func (r *reader) leave() {
+	r.depth--
+}
+
+func (r *reader) source() *source {
+	if r.src == nil {
+		r.src = newSource(r.opts.File, r.s, r.base)
+	}
+	return r.src
+}
+
+// locate sets the file and position of ParseErrors
+func (r *reader) locate(err error) error {
+	if parseErr, ok := err.(*ParseError); ok {
+		pos := r.source().position(parseErr.idx)
+		parseErr.File = r.opts.File
+		parseErr.Offset, parseErr.Line, parseErr.Column, parseErr.RuneOffset = pos.Offset, pos.Line, pos.Column, pos.RuneOffset
+	}
+	return err
+}
+
+// annotate appends a caret snippet of the source to ParseErrors.
+// Other errors are prefixed with the "file:line:col" of idx, and followed by the snippet
+func (r *reader) annotate(err error, idx int) error {
+	if parseErr, ok := r.locate(err).(*ParseError); ok {
+		parseErr.snippet = r.source().snippet(parseErr.idx)
+		return parseErr
+	}
+	return errors.New(r.source().annotate(idx, err.Error()))
 }
 
 func ReadFully(sexpStr string) (sexp interface{}, err error) {
-	sexp, idx, err := Read(sexpStr, 0)
+	r := newReader(sexpStr, ReaderOptions{}, startPosition)
+	sexp, idx, err := r.read(0)
+	if err != nil {
+		return nil, err
+	}
+	idx, err = r.getNextNonWSP(idx)
+	if err != nil {
+		return nil, r.locate(err)
+	}
 	if idx != len(sexpStr) {
-		return nil, errors.New("expected a string containing a single sexp, but got: " + sexpStr)
+		return nil, r.locate(parseError(UnexpectedChar, idx, "expected a string containing a single sexp, but got: "+sexpStr).expecting("end of input", sexpStr, idx))
+	}
+	return sexp, nil
+}
+
+// ReadAll reads all forms in sexpStr, which may be separated and followed by whitespace and comments
+func ReadAll(sexpStr string) ([]interface{}, error) {
+	r := newReader(sexpStr, ReaderOptions{}, startPosition)
+	return r.readAll(false)
+}
+
+// ReadAllWithOptions is like ReadAll, but reads like ReadWithOptions does
+func ReadAllWithOptions(sexpStr string, opts ReaderOptions) ([]interface{}, error) {
+	r := newReader(sexpStr, opts, startPosition)
+	return r.readAll(true)
+}
+
+func (r *reader) readAll(annotate bool) ([]interface{}, error) {
+	var sexps []interface{}
+	idx := 0
+	for {
+		sexp, nextIdx, found, err := r.readNext(idx)
+		if err != nil {
+			if annotate {
+				err = r.annotate(err, nextIdx)
+			}
+			return nil, err
+		}
+		if !found {
+			return sexps, nil
+		}
+		sexps = append(sexps, sexp)
+		idx = nextIdx
+	}
+}
+
+// InputStatus tells whether ReadPartial found a complete form
+type InputStatus int
+
+const (
+	// InputComplete means a form was read
+	InputComplete InputStatus = iota
+	// InputIncomplete means the input ends before the form does, e.g. inside a list that is not closed yet
+	InputIncomplete
+	// InputEmpty means the input contains nothing but whitespace and comments
+	InputEmpty
+	// InputError means the input is not valid, no matter what is appended to it
+	InputError
+)
+
+// ReadPartial reads the first form of input that may not have been entered completely yet, like the lines typed
+// into an interactive prompt so far. If the form is complete, rest is the text following it, otherwise rest is the
+// whole input for InputIncomplete and InputEmpty, or the text starting at the offending character for InputError
+func ReadPartial(sexpStr string) (sexp interface{}, status InputStatus, rest string, err error) {
+	r := newReader(sexpStr, ReaderOptions{}, startPosition)
+	sexp, idx, found, err := r.readNext(0)
+	switch {
+	case isIncomplete(err):
+		return nil, InputIncomplete, sexpStr, nil
+	case err != nil:
+		if idx > len(sexpStr) {
+			idx = len(sexpStr)
+		}
+		return nil, InputError, sexpStr[idx:], err
+	case !found:
+		return nil, InputEmpty, sexpStr, nil
+	default:
+		return sexp, InputComplete, sexpStr[idx:], nil
 	}
-	return sexp, err
 }
 
 func ReadEval(lexicalScope []map[string]interface{}, sexpStr string) (result interface{}, err error) {
@@ -39,18 +309,49 @@ func ReadEval(lexicalScope []map[string]interface{}, sexpStr string) (result int
 	return Eval(StdEnv, lexicalScope, expr)
 }
 
+// EvalAll evaluates sexps in order, and returns their results
+func EvalAll(env map[string]interface{}, lexicalScope []map[string]interface{}, sexps []interface{}) ([]interface{}, error) {
+	results := make([]interface{}, len(sexps))
+	for i, sexp := range sexps {
+		result, err := Eval(env, lexicalScope, sexp)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
 // functions must have this interface: func(fnName string, args []interface{}) (interface{}, error)
 // special forms must have this interface: func(env map[string]interface{}, lexicalScope []map[string]interface{}, fnName string, args []interface{}) (interface{}, error)
 func Eval(env map[string]interface{}, lexicalScope []map[string]interface{}, sexp interface{}) (result interface{}, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			result = nil
-			var ok bool
-			err, ok = r.(error)
-			if !ok {
-				err = fmt.Errorf("minsexp: %v", r)
+			err = errors.WithStack(panicError(r))
+		}
+	}()
+	result, err = eval(env, lexicalScope, sexp)
+	// errors are returned as the functions and special forms returned them, where they occurred is only of use to
+	// SourceMap.Eval
+	if evalErr, ok := err.(*evalError); ok {
+		return nil, evalErr.err
+	}
+	return result, err
+}
+
+// eval is like Eval, but returns errors as *evalError, remembering where in sexp they occurred, and passes on panics
+// as *evalPanic
+func eval(env map[string]interface{}, lexicalScope []map[string]interface{}, sexp interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// the innermost list a panic passes through is where it occurred
+			if list, ok := sexp.([]interface{}); ok && len(list) > 0 {
+				if _, ok := r.(*evalPanic); !ok {
+					r = &evalPanic{r, &list[0]}
+				}
 			}
-			err = errors.WithStack(err)
+			panic(r)
 		}
 	}()
 	switch sexp := sexp.(type) {
@@ -59,9 +360,27 @@ func Eval(env map[string]interface{}, lexicalScope []map[string]interface{}, sex
 			return nil, nil
 		}
 
+		if sexp[0] == Symbol("quote") {
+			if len(sexp) != 2 {
+				return nil, listError(errors.New("quote expects exactly one argument"), sexp)
+			}
+			return sexp[1], nil
+		}
+
+		if sexp[0] == Symbol("quasiquote") {
+			if len(sexp) != 2 {
+				return nil, listError(errors.New("quasiquote expects exactly one argument"), sexp)
+			}
+			result, err := quasiquote(env, lexicalScope, sexp[1])
+			if err != nil {
+				return nil, elemError(err, sexp, 1)
+			}
+			return result, nil
+		}
+
 		if sexp[0] == Symbol("let") {
 			if len(sexp)%2 != 0 {
-				return nil, errors.New("let needs an uneven number of arguments: name/sexp pairs and one sexp")
+				return nil, listError(errors.New("let needs an uneven number of arguments: name/sexp pairs and one sexp"), sexp)
 			}
 
 			//nameValuePairCount := (len(sexp) - 2) / 2
@@ -72,96 +391,316 @@ func Eval(env map[string]interface{}, lexicalScope []map[string]interface{}, sex
 			newLexicalScope = append(newLexicalScope, newLexicalScopeMap)
 			for i := 1; i+1 < len(sexp); i += 2 {
 				if nameSymbol, ok := sexp[i].(Symbol); ok {
-					value, err := Eval(env, newLexicalScope, sexp[i+1])
+					value, err := eval(env, newLexicalScope, sexp[i+1])
 					if err != nil {
-						return nil, err
+						return nil, elemError(err, sexp, i+1)
 					}
 					newLexicalScopeMap[string(nameSymbol)] = value
 				} else {
-					return nil, errors.New("let needs an uneven number of arguments: name-symbol/sexp pairs and one sexp")
+					return nil, elemError(errors.New("let needs an uneven number of arguments: name-symbol/sexp pairs and one sexp"), sexp, i)
 				}
 			}
-			return Eval(env, newLexicalScope, sexp[len(sexp)-1])
+			result, err := eval(env, newLexicalScope, sexp[len(sexp)-1])
+			if err != nil {
+				return nil, elemError(err, sexp, len(sexp)-1)
+			}
+			return result, nil
 		}
 
-		fnOrSpecialForm, fnErr := Eval(env, lexicalScope, sexp[0])
+		fnOrSpecialForm, fnErr := eval(env, lexicalScope, sexp[0])
 		if fnErr != nil {
-			return nil, fnErr
+			return nil, elemError(fnErr, sexp, 0)
 		}
 		if funFn, ok := fnOrSpecialForm.(func([]interface{}) (interface{}, error)); ok {
 			args := make([]interface{}, len(sexp)-1)
 			for i, v := range sexp[1:] {
-				out, fnErr := Eval(env, lexicalScope, v)
+				out, fnErr := eval(env, lexicalScope, v)
 				if fnErr != nil {
-					return nil, fnErr
+					return nil, elemError(fnErr, sexp, i+1)
 				}
 				args[i] = out
 			}
-			return funFn(args)
+			result, fnErr := funFn(args)
+			if fnErr != nil {
+				return nil, listError(fnErr, sexp)
+			}
+			return result, nil
 		} else if specialForm, ok := fnOrSpecialForm.(func(map[string]interface{}, []map[string]interface{}, []interface{}) (interface{}, error)); ok {
-			return specialForm(env, lexicalScope, sexp[1:])
+			result, fnErr := specialForm(env, lexicalScope, sexp[1:])
+			if fnErr != nil {
+				return nil, listError(fnErr, sexp)
+			}
+			return result, nil
 		} else {
-			return nil, errors.New(fmt.Sprintf("Not a special form and not a function: %v", sexp[0]))
+			return nil, elemError(errors.New(fmt.Sprintf("Not a special form and not a function: %v", sexp[0])), sexp, 0)
 		}
-	case Symbol:
-		for i := len(lexicalScope) - 1; i >= 0; i-- {
-			m := lexicalScope[i]
-			v, ok := m[string(sexp)]
-			if ok {
-				return v, nil
+	case Vector:
+		result := make(Vector, len(sexp))
+		for i, v := range sexp {
+			out, err := eval(env, lexicalScope, v)
+			if err != nil {
+				return nil, elemError(err, sexp, i)
 			}
+			result[i] = out
 		}
-		v, ok := env[string(sexp)]
-		if ok {
+		return result, nil
+	case Map:
+		result := make(Map, 0, len(sexp))
+		keys := newMapKeys(len(sexp))
+		for i := range sexp {
+			key, err := eval(env, lexicalScope, sexp[i].Key)
+			if err != nil {
+				return nil, entryError(err, sexp, i, true)
+			}
+			if !keys.add(key) {
+				return nil, entryError(errors.New(fmt.Sprintf("duplicate key in map: %v", Print(key))), sexp, i, true)
+			}
+			value, err := eval(env, lexicalScope, sexp[i].Value)
+			if err != nil {
+				return nil, entryError(err, sexp, i, false)
+			}
+			result = append(result, MapEntry{key, value})
+		}
+		return result, nil
+	case Symbol:
+		if v, ok := lookup(env, lexicalScope, string(sexp)); ok {
 			return v, nil
-		} else {
-			return nil, errors.New("Unbound name " + string(sexp))
 		}
+		if nsName := sexp.Namespace(); nsName != "" {
+			if ns, ok := lookup(env, lexicalScope, nsName); ok {
+				if ns, ok := ns.(Namespace); ok {
+					if v, ok := ns[sexp.Name()]; ok {
+						return v, nil
+					}
+				}
+			}
+		}
+		return nil, errors.New("Unbound name " + string(sexp))
 	default:
 		return sexp, nil
 	}
 }
 
-func Print(sexpI interface{}) (s string) {
-	defer func() {
-		if r := recover(); r != nil {
-			var ok bool
-			err, ok := r.(error)
-			if !ok {
-				err = fmt.Errorf("minsexp: %v", r)
-			}
-			s = err.Error()
+// lookup looks name up in the lexical scope, innermost first, and then in env
+func lookup(env map[string]interface{}, lexicalScope []map[string]interface{}, name string) (interface{}, bool) {
+	for i := len(lexicalScope) - 1; i >= 0; i-- {
+		if v, ok := lexicalScope[i][name]; ok {
+			return v, true
 		}
-	}()
-	switch sexp := sexpI.(type) {
-	case []interface{}:
-		s := "("
-		for _, v := range sexp {
-			if s != "(" {
-				s += " "
-			}
-			s += Print(v)
+	}
+	v, ok := env[name]
+	return v, ok
+}
+
+// readerMacroPrefixes are printed in place of the lists the reader turns them into
+var readerMacroPrefixes = map[Symbol]string{
+	"quote":            "'",
+	"quasiquote":       "`",
+	"unquote":          "~",
+	"unquote-splicing": "~@",
+}
+
+// evalError is returned by eval, and remembers where in the evaluated sexp an error occurred,
+// so that SourceMap.Eval can locate it in the source
+type evalError struct {
+	err    error
+	slot   *interface{} // the first element of the failing list, or the list element holding the failing form
+	isList bool
+	msg    string // set by SourceMap.Eval
+}
+
+func (e *evalError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return e.err.Error()
+}
+
+func (e *evalError) Cause() error  { return e.err }
+func (e *evalError) Unwrap() error { return e.err }
+
+func (e *evalError) StackTrace() errors.StackTrace {
+	if stackTracer, ok := e.err.(interface{ StackTrace() errors.StackTrace }); ok {
+		return stackTracer.StackTrace()
+	}
+	return nil
+}
+
+func (e *evalError) Format(s fmt.State, verb rune) {
+	if formatter, ok := e.err.(fmt.Formatter); ok && e.msg == "" {
+		formatter.Format(s, verb)
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+// evalPanic is what eval panics with when evaluating a list panics
+type evalPanic struct {
+	value interface{}
+	slot  *interface{} // the first element of the list
+}
+
+// panicError returns the error to report a panic recovered from eval with
+func panicError(r interface{}) error {
+	if p, ok := r.(*evalPanic); ok {
+		r = p.value
+	}
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("minsexp: %v", r)
+}
+
+// listError attributes err to list, unless the location of err is already known
+func listError(err error, list []interface{}) error {
+	if _, ok := err.(*evalError); ok || len(list) == 0 {
+		return err
+	}
+	return &evalError{err: err, slot: &list[0], isList: true}
+}
+
+// elemError attributes err to the i-th element of list, unless the location of err is already known
+func elemError(err error, list []interface{}, i int) error {
+	if _, ok := err.(*evalError); ok {
+		return err
+	}
+	return &evalError{err: err, slot: &list[i]}
+}
+
+// entryError attributes err to the key or value of the i-th entry of m, unless the location of err is already known
+func entryError(err error, m Map, i int, isKey bool) error {
+	if _, ok := err.(*evalError); ok {
+		return err
+	}
+	if isKey {
+		return &evalError{err: err, slot: &m[i].Key}
+	}
+	return &evalError{err: err, slot: &m[i].Value}
+}
+
+// printString quotes s such that reading the result yields s again
+func printString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	segmentStartIdx := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		var escape string
+		switch {
+		case c == '"':
+			escape = `\"`
+		case c == '\\':
+			escape = `\\`
+		case c == '\n':
+			escape = `\n`
+		case c == '\t':
+			escape = `\t`
+		case c == '\r':
+			escape = `\r`
+		case c < 0x20 || c == 0x7f:
+			escape = fmt.Sprintf(`\u%04x`, c)
+		default:
+			continue
 		}
-		return s + ")"
-	case string:
-		return "\"" + sexp + "\""
-	default:
-		return fmt.Sprintf("%v", sexp)
+		sb.WriteString(s[segmentStartIdx:i])
+		sb.WriteString(escape)
+		segmentStartIdx = i + 1
 	}
+	sb.WriteString(s[segmentStartIdx:])
+	sb.WriteByte('"')
+	return sb.String()
 }
 
-func getNextNonWSP(s string, startIdx int) int {
+// getNextNonWSP returns the index of the next character that is neither whitespace nor part of a comment.
+// Comments are ';' line comments, '#| ... |#' block comments, which may be nested, and '#_', which discards the next form
+func (r *reader) getNextNonWSP(startIdx int) (int, error) {
+	s := r.s
+	// the number of forms still to be discarded, as in '#_ #_ a b'. They are read here rather than by recursing
+	// for every '#_', so that a long run of '#_' cannot exhaust the stack
+	discards := 0
 	for idx := startIdx; idx < len(s); idx++ {
+		if n := whitespaceLen(s, idx); n > 0 {
+			idx += n - 1
+			continue
+		}
 		switch s[idx] {
-		case ' ':
-		case '\t':
-		case '\r':
-		case '\n':
-		default:
-			return idx
+		case ';':
+			for idx+1 < len(s) && s[idx+1] != '\n' {
+				idx++
+			}
+			continue
+		case '#':
+			if idx+1 < len(s) && s[idx+1] == '|' {
+				endIdx, err := skipBlockComment(s, idx)
+				if err != nil {
+					return endIdx, err
+				}
+				idx = endIdx - 1
+				continue
+			} else if idx+1 < len(s) && s[idx+1] == '_' {
+				discards++
+				idx++
+				continue
+			}
+		}
+		if discards == 0 {
+			return idx, nil
+		}
+		discards--
+		var endIdx int
+		var err error
+		if r.cst {
+			_, endIdx, err = r.parseNode(idx)
+		} else {
+			_, endIdx, err = r.parseSexp(idx)
+		}
+		if err != nil {
+			return endIdx, err
 		}
+		idx = endIdx - 1
 	}
-	return len(s)
+	if discards > 0 {
+		// the input ends before the form to be discarded
+		if _, endIdx, err := r.parseSexp(len(s)); err != nil {
+			return endIdx, err
+		}
+	}
+	return len(s), nil
+}
+
+// skipBlockComment returns the index after the (possibly nested) block comment starting at startIdx
+func skipBlockComment(s string, startIdx int) (int, error) {
+	depth := 0
+	for idx := startIdx; idx+1 < len(s); idx++ {
+		if s[idx] == '#' && s[idx+1] == '|' {
+			depth++
+			idx++
+		} else if s[idx] == '|' && s[idx+1] == '#' {
+			depth--
+			idx++
+			if depth == 0 {
+				return idx + 1, nil
+			}
+		}
+	}
+	return len(s), parseError(Incomplete, len(s), "block comment not terminated by |#").expecting("'|#'", s, len(s))
+}
+
+// whitespaceLen returns the length in bytes of the whitespace character at idx, or 0 if there is none.
+// Any Unicode whitespace is accepted, e.g. U+00A0 NO-BREAK SPACE or U+3000 IDEOGRAPHIC SPACE
+func whitespaceLen(s string, idx int) int {
+	if c := s[idx]; c < utf8.RuneSelf {
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\v' || c == '\f' {
+			return 1
+		}
+		return 0
+	}
+	r, size := utf8.DecodeRuneInString(s[idx:])
+	if unicode.IsSpace(r) {
+		return size
+	}
+	return 0
 }
 
 func getNextNonSymbolChar(s string, startIdx int) int {
@@ -188,61 +727,343 @@ func getNextNonSymbolChar(s string, startIdx int) int {
 		case '\r':
 			fallthrough
 		case '\n':
+			fallthrough
+		case ';':
 			return idx
+		default:
+			if whitespaceLen(s, idx) > 0 {
+				return idx
+			}
 		}
 	}
 	return len(s)
 }
 
-func parseList(s string, startIdx int) ([]interface{}, int, error) {
-	//fmt.Println("parseList", startIdx)
-	if s[startIdx] != '(' {
-		return nil, startIdx, errors.New("expecting '(' at start of list")
+func (r *reader) parseList(startIdx int) (interface{}, int, error) {
+	list, elemBounds, idx, err := r.parseSeq(startIdx, '(', ')', "list")
+	if err != nil {
+		return nil, idx, err
+	}
+	if r.opts.SourceMap != nil {
+		r.opts.SourceMap.record(list, r.src.span(startIdx, idx), r.spans(elemBounds))
+	}
+	return list, idx, nil
+}
+
+func (r *reader) parseVector(startIdx int) (interface{}, int, error) {
+	list, elemBounds, idx, err := r.parseSeq(startIdx, '[', ']', "vector")
+	if err != nil {
+		return nil, idx, err
+	}
+	if r.opts.SourceMap != nil {
+		r.opts.SourceMap.record(list, r.src.span(startIdx, idx), r.spans(elemBounds))
+	}
+	return Vector(list), idx, nil
+}
+
+func (r *reader) parseMap(startIdx int) (interface{}, int, error) {
+	list, elemBounds, idx, err := r.parseSeq(startIdx, '{', '}', "map")
+	if err != nil {
+		return nil, idx, err
+	}
+	if len(list)%2 != 0 {
+		// point at the closing '}', or at the last key if the map is not closed when recovering from errors
+		errIdx, foundIdx := idx-1, idx-1
+		if r.s[idx-1] != '}' {
+			errIdx, foundIdx = elemBounds[len(elemBounds)-1].startIdx, idx
+		}
+		err := parseError(UnexpectedChar, errIdx, "map needs an even number of forms: key/value pairs").expecting("value", r.s, foundIdx)
+		if _, _, err := r.skip(err, errIdx, idx); err != nil {
+			return nil, errIdx, err
+		}
+		list, elemBounds = list[:len(list)-1], elemBounds[:len(elemBounds)-1]
 	}
+	m := make(Map, 0, len(list)/2)
+	entryBounds := make([]bounds, 0, len(elemBounds))
+	keys := newMapKeys(len(list) / 2)
+	for i := 0; i < len(list); i += 2 {
+		if !keys.add(list[i]) {
+			err := parseError(DuplicateKey, elemBounds[i].startIdx, "duplicate key in map: "+Print(list[i]))
+			if _, _, err := r.skip(err, elemBounds[i].startIdx, idx); err != nil {
+				return nil, elemBounds[i].startIdx, err
+			}
+			continue
+		}
+		m = append(m, MapEntry{list[i], list[i+1]})
+		entryBounds = append(entryBounds, elemBounds[i], elemBounds[i+1])
+	}
+	if r.opts.SourceMap != nil {
+		r.opts.SourceMap.recordMap(m, r.src.span(startIdx, idx), r.spans(entryBounds))
+	}
+	return m, idx, nil
+}
+
+// parsePrefixed reads a form preceded by a prefix of prefixLen characters, like 'x, as a list (sym x)
+func (r *reader) parsePrefixed(startIdx int, prefixLen int, sym Symbol) (interface{}, int, error) {
+	defer r.leave()
+	if err := r.enter(startIdx); err != nil {
+		return nil, startIdx, err
+	}
+	formStartIdx, err := r.getNextNonWSP(startIdx + prefixLen)
+	if err != nil {
+		return r.skip(err, formStartIdx, formStartIdx)
+	}
+	form, idx, err := r.parseSexp(formStartIdx)
+	if err != nil {
+		return nil, idx, err
+	}
+	if _, ok := form.(skippedForm); ok {
+		return form, idx, nil
+	}
+	list := []interface{}{sym, form}
+	if r.opts.SourceMap != nil {
+		r.opts.SourceMap.record(list, r.src.span(startIdx, idx), r.spans([]bounds{{startIdx, startIdx + prefixLen}, {formStartIdx, idx}}))
+	}
+	return list, idx, nil
+}
+
+type bounds struct {
+	startIdx int
+	endIdx   int
+}
+
+func (r *reader) spans(elemBounds []bounds) []Span {
+	spans := make([]Span, len(elemBounds))
+	for i, b := range elemBounds {
+		spans[i] = r.src.span(b.startIdx, b.endIdx)
+	}
+	return spans
+}
+
+// parseSeq parses the forms enclosed by opening and closing.
+// Where the forms start and end is only returned for maps, or if there is a SourceMap
+func (r *reader) parseSeq(startIdx int, opening byte, closing byte, kind string) ([]interface{}, []bounds, int, error) {
+	//fmt.Println("parseSeq", startIdx)
+	s := r.s
+	if s[startIdx] != opening {
+		return nil, nil, startIdx, parseError(UnexpectedChar, startIdx, fmt.Sprintf("expecting '%c' at start of %v", opening, kind)).expecting(fmt.Sprintf("'%c'", opening), s, startIdx)
+	}
+	defer r.leave()
+	if err := r.enter(startIdx); err != nil {
+		return nil, nil, startIdx, err
+	}
+	if r.recovering {
+		r.closers = append(r.closers, closing)
+		defer func() { r.closers = r.closers[:len(r.closers)-1] }()
+	}
+	withBounds := opening == '{' || r.opts.SourceMap != nil
+	seqStartIdx := startIdx
 	startIdx++
 	var list []interface{}
+	var elemBounds []bounds
 	for {
-		i := getNextNonWSP(s, startIdx)
+		i, err := r.getNextNonWSP(startIdx)
+		if err != nil {
+			if _, i, err = r.skip(err, i, i); err != nil {
+				return nil, nil, i, err
+			}
+		}
 		if i >= len(s) {
-			return nil, i, errors.New("reached end of input parsing list")
+			msg := fmt.Sprintf("reached end of input parsing %v starting at %v", kind, r.source().position(seqStartIdx))
+			if _, _, err := r.skip(parseError(Incomplete, i, msg).expecting(fmt.Sprintf("'%c'", closing), s, i), i, i); err != nil {
+				return nil, nil, i, err
+			}
+			return list, elemBounds, i, nil
 		}
-		if s[i] == ')' {
-			return list, i + 1, nil
+		switch s[i] {
+		case closing:
+			return list, elemBounds, i + 1, nil
+		case ')', ']', '}':
+			msg := fmt.Sprintf("expected '%c' to close %v starting at %v, but got '%c'", closing, kind, r.source().position(seqStartIdx), s[i])
+			if _, _, err := r.skip(parseError(UnbalancedParen, i, msg).expecting(fmt.Sprintf("'%c'", closing), s, i), i, i); err != nil {
+				return nil, nil, i, err
+			}
+			// leave the closer to the enclosing form it belongs to, or skip it if there is none
+			if r.isEnclosingCloser(s[i]) {
+				return list, elemBounds, i, nil
+			}
+			startIdx = i + 1
+			continue
 		}
 		var value interface{}
-		var err error
-		value, startIdx, err = parseSexp(s, i)
+		value, startIdx, err = r.parseSexp(i)
 		if err != nil {
-			return nil, startIdx, err
+			return nil, nil, startIdx, err
+		}
+		if _, ok := value.(skippedForm); ok {
+			continue
 		}
 		list = append(list, value)
+		if withBounds {
+			elemBounds = append(elemBounds, bounds{i, startIdx})
+		}
 	}
 }
 
-func parseSymbol(s string, startIdx int) (Symbol, int, error) {
+func (r *reader) parseSymbol(startIdx int) (Symbol, int, error) {
 	//fmt.Println("parseSymbol", startIdx)
+	i := getNextNonSymbolChar(r.s, startIdx)
+	name, errIdx, err := r.symbolName(startIdx, i)
+	if err != nil {
+		return "", errIdx, err
+	}
+	// a '/' separates the namespace from the name, neither of which may be empty. "/" itself, and the name "/" in a
+	// namespace, like ns//, are allowed
+	if len(name) > 1 && (name[0] == '/' || (name[len(name)-1] == '/' && Symbol(name).Namespace() == "")) {
+		err := parseError(UnexpectedChar, startIdx, "symbol with empty namespace or name: "+name)
+		if name[0] != '/' && i == len(r.s) {
+			// the name may still follow
+			err = err.expecting("name", r.s, i)
+		}
+		return "", startIdx, err
+	}
+	return Symbol(name), i, nil
+}
+
+func (r *reader) parseKeyword(startIdx int) (Keyword, int, error) {
+	s := r.s
 	i := getNextNonSymbolChar(s, startIdx)
-	return Symbol(s[startIdx:i]), i, nil
+	if i == startIdx+1 {
+		return "", startIdx, parseError(UnexpectedChar, startIdx, "keyword has no name").expecting("keyword name", s, i)
+	}
+	name, errIdx, err := r.symbolName(startIdx+1, i)
+	if err != nil {
+		return "", errIdx, err
+	}
+	return Keyword(name), i, nil
+}
+
+// symbolicValues are the floats that have no decimal representation, by the name they are written with after ##
+var symbolicValues = map[string]float64{"Inf": math.Inf(1), "-Inf": math.Inf(-1), "NaN": math.NaN()}
+
+// parseSymbolicValue parses ##Inf, ##-Inf and ##NaN, which Print prints infinite and NaN floats as, into float64s
+func (r *reader) parseSymbolicValue(startIdx int) (interface{}, int, error) {
+	s := r.s
+	i := getNextNonSymbolChar(s, startIdx+2)
+	if f, ok := symbolicValues[s[startIdx+2:i]]; ok {
+		return f, i, nil
+	}
+	err := parseError(UnexpectedChar, startIdx, "unknown symbolic value: "+s[startIdx:i])
+	if i == len(s) {
+		// the rest of the name may still follow
+		err = err.expecting("Inf, -Inf or NaN", s, i)
+	}
+	return nil, startIdx, err
+}
+
+// symbolName returns the name of the symbol or keyword between startIdx and endIdx, normalized if requested.
+// If the name is not valid UTF-8, the index of the first invalid byte is returned along with the error
+func (r *reader) symbolName(startIdx int, endIdx int) (string, int, error) {
+	name := r.s[startIdx:endIdx]
+	if !utf8.ValidString(name) {
+		for i := startIdx; i < endIdx; {
+			c, size := utf8.DecodeRuneInString(r.s[i:endIdx])
+			if c == utf8.RuneError && size == 1 {
+				return "", i, parseError(UnexpectedChar, i, "invalid UTF-8 in symbol: "+strconv.Quote(name))
+			}
+			i += size
+		}
+	}
+	if r.opts.NormalizeSymbol != nil {
+		name = r.opts.NormalizeSymbol(name)
+	}
+	return name, endIdx, nil
 }
 
 func parseString(s string, startIdx int) (string, int, error) {
 	//fmt.Println("parseString", startIdx)
 	if s[startIdx] != '"' {
-		return "", startIdx, errors.New("expecting '\"' at start of string")
+		return "", startIdx, parseError(UnexpectedChar, startIdx, "expecting '\"' at start of string").expecting("'\"'", s, startIdx)
 	}
 
+	var sb strings.Builder
+	escaped := false
+	segmentStartIdx := startIdx + 1
 	j := startIdx + 1
-	for ; j < len(s); j++ {
-		// todo: handle '\' escapes
-		if s[j] == '"' {
-			return s[startIdx+1 : j], j + 1, nil
+	for j < len(s) {
+		switch s[j] {
+		case '"':
+			if !escaped {
+				return s[segmentStartIdx:j], j + 1, nil
+			}
+			sb.WriteString(s[segmentStartIdx:j])
+			return sb.String(), j + 1, nil
+		case '\\':
+			sb.WriteString(s[segmentStartIdx:j])
+			escaped = true
+			var err error
+			j, err = parseEscape(s, j, &sb)
+			if err != nil {
+				return "", j, err
+			}
+			segmentStartIdx = j
+		default:
+			j++
 		}
 	}
-	return "", j, errors.New(fmt.Sprintf("string starting at %v not terminated by double quote", startIdx))
+	return "", j, parseError(UnterminatedString, j, "string not terminated by double quote").expecting("'\"'", s, j)
 }
 
-func isAfterNumber(c uint8) bool {
-	switch c {
+// parseEscape writes the character denoted by the escape sequence starting at startIdx to sb,
+// and returns the index after the escape sequence
+func parseEscape(s string, startIdx int, sb *strings.Builder) (int, error) {
+	if startIdx+1 >= len(s) {
+		return startIdx, parseError(UnterminatedString, startIdx, "unterminated escape sequence").expecting("escape sequence", s, startIdx+1)
+	}
+	switch c := s[startIdx+1]; c {
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'u':
+		r, idx, err := parseUnicodeEscape(s, startIdx)
+		if err != nil {
+			return idx, err
+		}
+		if utf16.IsSurrogate(r) {
+			// a surrogate pair, as produced by e.g. JSON encoders for characters outside the BMP
+			if idx+1 < len(s) && s[idx] == '\\' && s[idx+1] == 'u' {
+				r2, idx2, err := parseUnicodeEscape(s, idx)
+				if err != nil {
+					return idx2, err
+				}
+				if combined := utf16.DecodeRune(r, r2); combined != utf8.RuneError {
+					sb.WriteRune(combined)
+					return idx2, nil
+				}
+			} else if r < 0xdc00 && (idx == len(s) || (idx+1 == len(s) && s[idx] == '\\')) {
+				// the input ends where the low surrogate would follow
+				return startIdx, parseError(UnterminatedString, startIdx, "unterminated surrogate pair in escape sequence: "+s[startIdx:]).expecting("low surrogate escape", s, len(s))
+			}
+			return startIdx, parseError(UnexpectedChar, startIdx, "invalid surrogate in escape sequence: "+s[startIdx:idx])
+		}
+		sb.WriteRune(r)
+		return idx, nil
+	default:
+		return startIdx, parseError(UnexpectedChar, startIdx, fmt.Sprintf("invalid escape sequence: \\%c", c)).expecting("escape sequence", s, startIdx+1)
+	}
+	return startIdx + 2, nil
+}
+
+// parseUnicodeEscape parses a \uXXXX escape sequence starting at startIdx
+func parseUnicodeEscape(s string, startIdx int) (rune, int, error) {
+	endIdx := startIdx + 6
+	if endIdx > len(s) {
+		return 0, startIdx, parseError(UnterminatedString, startIdx, "\\u escape sequence needs 4 hex digits").expecting("hex digit", s, len(s))
+	}
+	code, err := strconv.ParseUint(s[startIdx+2:endIdx], 16, 16)
+	if err != nil {
+		return 0, startIdx, parseError(UnexpectedChar, startIdx, "\\u escape sequence needs 4 hex digits: "+s[startIdx:endIdx])
+	}
+	return rune(code), endIdx, nil
+}
+
+func isAfterNumber(s string, idx int) bool {
+	switch s[idx] {
 	case ' ':
 		fallthrough
 	case '\t':
@@ -251,63 +1072,220 @@ func isAfterNumber(c uint8) bool {
 		fallthrough
 	case '\n':
 		fallthrough
+	case ';':
+		fallthrough
 	case ')':
+		fallthrough
+	case ']':
+		fallthrough
+	case '}':
 		return true
 	default:
-		return false
+		return whitespaceLen(s, idx) > 0
 	}
-
 }
+
+// maxExponent is the largest absolute value of the exponent of numbers like 1e100
+const maxExponent = 1000
+
+// parseNumber parses decimal numbers with an optional fraction and exponent (e.g. -1.5e-3),
+// and integers in hexadecimal (0x1F), binary (0b101) and octal (0o17) notation.
+// Digits may be grouped using underscores, e.g. 1_000_000
 func parseNumber(s string, startIdx int) (interface{}, int, error) {
-	// todo: allow scientific notation
-	afterSignIdx := startIdx
-	firstChar := s[startIdx]
-	if firstChar == '-' || firstChar == '+' {
-		afterSignIdx++
-	}
-	dotIdx := -1
-	for i := startIdx + 1; i <= len(s); i++ {
-		if i == len(s) || isAfterNumber(s[i]) {
-			if i-1 == dotIdx {
-				return nil, i - 1, errors.New(fmt.Sprintf("not a valid number at %v: %v", i-1, s[startIdx:i]))
-			}
-			f, e := decimal.NewFromString(s[startIdx:i])
-			if e != nil {
-				return nil, i, e
-			} else {
-				return f, i, nil
-			}
+	i := startIdx
+	negative := s[i] == '-'
+	if s[i] == '-' || s[i] == '+' {
+		i++
+	}
+
+	base := 10
+	if i+1 < len(s) && s[i] == '0' {
+		switch s[i+1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+	if base != 10 {
+		digitsStartIdx := i + 2
+		i, err := scanDigits(s, startIdx, digitsStartIdx, base)
+		if err != nil {
+			return nil, i, err
+		}
+		if i < len(s) && !isAfterNumber(s, i) {
+			return nil, i, unexpectedNumberCharError(s, startIdx, i)
+		}
+		n, ok := new(big.Int).SetString(strings.Replace(s[digitsStartIdx:i], "_", "", -1), base)
+		if !ok {
+			return nil, i, parseError(BadNumber, startIdx, "not a valid number: "+s[startIdx:i])
 		}
+		if negative {
+			n.Neg(n)
+		}
+		return decimal.NewFromBigInt(n, 0), i, nil
+	}
 
+	i, err := scanDigits(s, startIdx, i, 10)
+	if err != nil {
+		return nil, i, err
+	}
+	if i < len(s) && s[i] == '.' {
+		if i+1 == len(s) || isAfterNumber(s, i+1) {
+			return nil, i, parseError(BadNumber, i, "not a valid number, expected digit after '.': "+s[startIdx:i+1]).expecting("digit", s, i+1)
+		}
+		if i, err = scanDigits(s, startIdx, i+1, 10); err != nil {
+			return nil, i, err
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		expIdx := i
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		if i == len(s) || isAfterNumber(s, i) {
+			return nil, expIdx, parseError(BadNumber, expIdx, "not a valid number, expected digit in exponent: "+s[startIdx:i]).expecting("digit", s, i)
+		}
+		digitsStartIdx := i
+		if i, err = scanDigits(s, startIdx, i, 10); err != nil {
+			return nil, i, err
+		}
+		// decimals with huge exponents take huge amounts of time and memory to compute with and to print
+		if exp, err := strconv.Atoi(strings.Replace(s[digitsStartIdx:i], "_", "", -1)); err != nil || exp > maxExponent {
+			return nil, expIdx, parseError(BadNumber, expIdx, fmt.Sprintf("not a valid number, exponent larger than %d: %v", maxExponent, s[startIdx:i]))
+		}
+	}
+	if i < len(s) && !isAfterNumber(s, i) {
+		return nil, i, unexpectedNumberCharError(s, startIdx, i)
+	}
+
+	f, e := decimal.NewFromString(strings.Replace(s[startIdx:i], "_", "", -1))
+	if e != nil {
+		return nil, startIdx, parseError(BadNumber, startIdx, fmt.Sprintf("not a valid number: %v: %v", s[startIdx:i], e))
+	}
+	return f, i, nil
+}
+
+// scanDigits returns the index after the digits starting at startIdx. There has to be at least one digit,
+// and underscores are only allowed between digits
+func scanDigits(s string, numberStartIdx int, startIdx int, base int) (int, error) {
+	i := startIdx
+	for ; i < len(s); i++ {
 		c := s[i]
-		if (c < '0' || c > '9') && (c != '.' || dotIdx > -1 || i == afterSignIdx) {
-			return nil, i, errors.New(fmt.Sprintf("not a valid number at %v: %v", i, s[startIdx:i+1]))
+		if c == '_' && i > startIdx && i+1 < len(s) && isDigit(s[i+1], base) {
+			continue
+		}
+		if !isDigit(c, base) {
+			break
 		}
-		if c == '.' {
-			dotIdx = i
+	}
+	if i == startIdx {
+		if i == len(s) || isAfterNumber(s, i) {
+			return i, parseError(BadNumber, i, "not a valid number, expected digit: "+s[numberStartIdx:i]).expecting("digit", s, i)
 		}
+		return i, unexpectedNumberCharError(s, numberStartIdx, i)
 	}
-	return nil, len(s), errors.New("impossible error")
+	if i+1 == len(s) && s[i] == '_' {
+		// more digits may follow the underscore
+		return i, parseError(BadNumber, i, "not a valid number, expected digit after '_': "+s[numberStartIdx:]).expecting("digit", s, len(s))
+	}
+	return i, nil
+}
+
+func isDigit(c uint8, base int) bool {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') < base
+	case c >= 'a' && c <= 'f':
+		return base == 16
+	case c >= 'A' && c <= 'F':
+		return base == 16
+	default:
+		return false
+	}
+}
+
+func unexpectedNumberCharError(s string, startIdx int, idx int) error {
+	c, size := utf8.DecodeRuneInString(s[idx:])
+	return parseError(BadNumber, idx, fmt.Sprintf("not a valid number, unexpected character '%c': %v", c, s[startIdx:idx+size])).expecting("digit", s, idx)
 }
 
-func parseSexp(s string, startIdx int) (value interface{}, nextIndex int, err error) {
-	i := getNextNonWSP(s, startIdx)
+func (r *reader) parseSexp(startIdx int) (value interface{}, nextIndex int, err error) {
+	s := r.s
+	i, err := r.getNextNonWSP(startIdx)
+	if err != nil {
+		return r.skip(err, i, i)
+	}
 	if i >= len(s) {
-		return nil, i, errors.New("reached end of input parsing sexp")
+		return r.skip(parseError(Incomplete, i, "reached end of input parsing sexp").expecting("sexp", s, i), i, i)
+	}
+	r.forms++
+	if max := r.opts.MaxForms; max > 0 && r.forms > max {
+		return nil, i, parseError(LimitExceeded, i, fmt.Sprintf("more forms than MaxForms (%d)", max))
+	}
+	value, nextIndex, err = r.parseForm(i)
+	if err != nil {
+		return r.skip(err, nextIndex, r.resyncIdx(i))
+	}
+	return value, nextIndex, nil
+}
+
+// parseForm parses the form starting at i
+func (r *reader) parseForm(i int) (interface{}, int, error) {
+	s := r.s
+	if rt := r.opts.Readtable; rt != nil {
+		if macro, prefixLen, ok := rt.prefixMacro(s, i); ok {
+			return r.parseMacro(i, prefixLen, macro)
+		}
+	}
+	if s[i] == '#' {
+		if macro, prefixLen, ok := r.dispatchMacro(i); ok {
+			return r.parseMacro(i, prefixLen, macro)
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			return r.parseRegex(i)
+		}
+		if i+1 < len(s) && s[i+1] == '#' {
+			return r.parseSymbolicValue(i)
+		}
+		// rather than a symbol, which would quietly leave the form following the tag unconverted
+		return nil, i, r.unknownTagError(i)
 	}
 	b := s[i]
 
 	switch b {
 	case '(':
-		return parseList(s, i)
+		return r.parseList(i)
+	case '[':
+		return r.parseVector(i)
+	case '{':
+		return r.parseMap(i)
 	case '"':
-		return parseString(s, i)
+		str, idx, err := parseString(s, i)
+		if max := r.opts.MaxStringLength; err == nil && max > 0 && len(str) > max {
+			return nil, i, parseError(LimitExceeded, i, fmt.Sprintf("string longer than MaxStringLength (%d)", max))
+		}
+		return str, idx, err
+	case ':':
+		return r.parseKeyword(i)
+	case '\'':
+		return r.parsePrefixed(i, 1, Symbol("quote"))
+	case '`':
+		return r.parsePrefixed(i, 1, Symbol("quasiquote"))
+	case '~':
+		if i+1 < len(s) && s[i+1] == '@' {
+			return r.parsePrefixed(i, 2, Symbol("unquote-splicing"))
+		}
+		return r.parsePrefixed(i, 1, Symbol("unquote"))
 
 	case '+':
 		fallthrough
 	case '-':
 		if i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9' {
-			return parseSymbol(s, i)
+			return r.parseSymbol(i)
 		}
 		return parseNumber(s, i)
 	case '0':
@@ -333,20 +1311,16 @@ func parseSexp(s string, startIdx int) (value interface{}, nextIndex int, err er
 
 	case ')':
 		fallthrough
-	case '[':
-		fallthrough
 	case ']':
 		fallthrough
-	case '{':
-		fallthrough
 	case '}':
-		fallthrough
+		return nil, i, parseError(UnbalancedParen, i, fmt.Sprintf("Syntax error. Unexpected character '%c'", b)).expecting("sexp", s, i)
 	case ',':
 		fallthrough
 	case '.':
-		return nil, i, errors.New(fmt.Sprintf("Syntax error. Unexpected character '%c'", b))
+		return nil, i, parseError(UnexpectedChar, i, fmt.Sprintf("Syntax error. Unexpected character '%c'", b)).expecting("sexp", s, i)
 
 	default:
-		return parseSymbol(s, i)
+		return r.parseSymbol(i)
 	}
 }
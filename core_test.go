@@ -120,8 +120,75 @@ func TestMalformedNumbers(t *testing.T) {
 	}
 }
 
+func TestNumberNotations(t *testing.T) {
+	for in, expectedOut := range map[string]string{
+		"1.5e-3":      "0.0015",
+		"1.5E+3":      "1500",
+		"6E23":        "600000000000000000000000",
+		"-2e2":        "-200",
+		"0x1F":        "31",
+		"0X1f":        "31",
+		"-0x10":       "-16",
+		"0b101":       "5",
+		"+0B1":        "1",
+		"0o17":        "15",
+		"1_000_000":   "1000000",
+		"1_000.000_5": "1000.0005",
+		"0xFF_FF":     "65535",
+		"0b1010_1010": "170",
+		"1_0e1_0":     "100000000000",
+		"007":         "7",
+		"1e1000":      "1" + strings.Repeat("0", 1000),
+		"1e-1000":     "0." + strings.Repeat("0", 999) + "1",
+	} {
+		expectedSexpOut, err := decimal.NewFromString(expectedOut)
+		require.Nil(t, err, in)
+		sexp, idx, err := Read(in, 0)
+		require.Nil(t, err, in)
+		require.Equal(t, len(in), idx, in)
+		require.Zero(t, expectedSexpOut.Cmp(sexp.(decimal.Decimal)), in)
+	}
+}
+
+func TestMalformedNumberNotations(t *testing.T) {
+	for in, expectedIdx := range map[string]int{
+		"1e":      1,
+		"1e+":     1,
+		"1ex":     2,
+		"1.5e3.2": 5,
+		"0x":      2,
+		"0xG":     2,
+		"0x1G":    3,
+		"0b102":   4,
+		"0o8":     2,
+		"0x1.5":   3,
+		"1_":      1,
+		"1__0":    1,
+		"1_.5":    1,
+		"1._5":    2,
+		"0x_1":    2,
+		"12a":     2,
+		"1.2.3":   3,
+		// exponents are limited, to avoid huge computations
+		"1e1001":                   1,
+		"-2.5E-1_001":              4,
+		"1e1000000000":             1,
+		"1e9999999999999999999999": 1,
+	} {
+		sexp, idx, err := Read(in, 0)
+		require.Nil(t, sexp, in)
+		require.NotNil(t, err, in)
+		require.Equal(t, expectedIdx, idx, in)
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, BadNumber, parseErr.Kind, in)
+		require.Equal(t, expectedIdx, parseErr.Offset, in)
+		require.Equal(t, expectedIdx+1, parseErr.Column, in)
+	}
+}
+
 func TestSymbols(t *testing.T) {
-	for _, v := range []string{"+", "-", "a", "and", "if", "nil", ":asdf", "let*"} {
+	for _, v := range []string{"+", "-", "a", "and", "if", "nil", "a:sdf", "let*"} {
 		testRead(t, v, Symbol(v), v)
 		testReadFully(t, v, Symbol(v), v)
 	}
@@ -219,3 +286,503 @@ func TestLetFails(t *testing.T) {
 		require.Nil(t, evalledSexp, inputForm)
 	}
 }
+
+func TestStrings(t *testing.T) {
+	for in, expectedOut := range map[string]string{
+		`""`:                   "",
+		`"abc"`:                "abc",
+		`"a\"b"`:               `a"b`,
+		`"a\\b"`:               `a\b`,
+		`"\n\t\r"`:             "\n\t\r",
+		`"äカ"`:                 "äカ",
+		`"😀"`:                  "😀",
+		`"äカ😀"`:                "äカ😀",
+		`"\\\"\\"`:             `\"\`,
+		`"line1\nline2 \"x\""`: "line1\nline2 \"x\"",
+	} {
+		testRead(t, in, expectedOut, in)
+		testReadFully(t, in, expectedOut, in)
+	}
+}
+
+func TestMalformedStrings(t *testing.T) {
+	for _, v := range []string{`"abc`, `"a\"`, `"a\`, `"\x"`, `"\u12"`, `"\u12g4"`, `"\ud83d"`, `"\ud83dA"`} {
+		_, _, err := Read(v, 0)
+		require.NotNil(t, err, v)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, v := range []string{"", "abc", `"`, `\`, `\"`, "\n\t\r", "\x00\x01\x1f\x7f", "äカ😀", "\xff\xfe invalid utf-8", `A`, "(a b)"} {
+		printed := Print(v)
+		testRead(t, printed, v, v)
+		testRead(t, Print([]interface{}{v, v}), []interface{}{v, v}, v)
+	}
+}
+
+func TestComments(t *testing.T) {
+	for in, expectedOut := range map[string]interface{}{
+		"; comment\n(+ 1 2)":                  []interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)},
+		"(+ 1 ; one\n 2 ; two\n)":             []interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)},
+		"(+ 1;one\n2)":                        []interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)},
+		"(a;comment\n)":                       []interface{}{Symbol("a")},
+		"#| block |# a":                       Symbol("a"),
+		"(a #| block |#)":                     []interface{}{Symbol("a")},
+		"(a #| outer #| inner |# outer |# b)": []interface{}{Symbol("a"), Symbol("b")},
+		"(a #|\n multi\n line\n|# b)":         []interface{}{Symbol("a"), Symbol("b")},
+		"#_ ignored a":                        Symbol("a"),
+		"(a #_b c)":                           []interface{}{Symbol("a"), Symbol("c")},
+		"(a #_(b (c)) d)":                     []interface{}{Symbol("a"), Symbol("d")},
+		"(a #_ #_ b c d)":                     []interface{}{Symbol("a"), Symbol("d")},
+		"(a #_b)":                             []interface{}{Symbol("a")},
+		"(a #_ ; comment\n b)":                []interface{}{Symbol("a")},
+		"(a#b)":                               []interface{}{Symbol("a#b")},
+	} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		require.Equal(t, expectedOut, sexp, in)
+	}
+}
+
+func TestReadFullyTrailingComments(t *testing.T) {
+	for _, in := range []string{"(a) ; comment", "(a)\n", "(a) #| block |#", "(a) #_ (b)", "(a) ;"} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		require.Equal(t, []interface{}{Symbol("a")}, sexp, in)
+	}
+}
+
+func TestMalformedComments(t *testing.T) {
+	for _, in := range []string{"#| a", "(a #| b", "#| #| a |#", "(a #_)", "#_", "(a #_ (b)", "(a) (b)"} {
+		_, err := ReadFully(in)
+		require.NotNil(t, err, in)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	for inputForm, expectedOutput := range map[string]interface{}{
+		"[]":              Vector{},
+		"[1 2 3]":         Vector{decimal.New(1, 0), decimal.New(2, 0), decimal.New(3, 0)},
+		"[(+ 1 2) \"a\"]": Vector{decimal.New(3, 0), "a"},
+		"[[a] [nil]]":     Vector{Vector{decimal.New(5, 0)}, Vector{nil}},
+		"(let a 1 [a])":   Vector{decimal.New(1, 0)},
+	} {
+		readSexp, idx, err := Read(inputForm, 0)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, idx, len(inputForm), inputForm)
+
+		printed := Print(readSexp)
+		require.Equal(t, inputForm, printed)
+
+		evalledSexp, err := Eval(StdEnv, []map[string]interface{}{{"a": decimal.New(5, 0)}}, readSexp)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expectedOutput, evalledSexp), inputForm)
+	}
+}
+
+func TestReadVectors(t *testing.T) {
+	testRead(t, "[a (b) [c]]", Vector{Symbol("a"), []interface{}{Symbol("b")}, Vector{Symbol("c")}})
+	testRead(t, "[1]", Vector{decimal.New(1, 0)})
+	for _, in := range []string{"[1 2", "[1 2)", "(1 2]", "]", "[(]"} {
+		_, _, err := Read(in, 0)
+		require.NotNil(t, err, in)
+	}
+}
+
+func TestMaps(t *testing.T) {
+	for inputForm, expectedOutput := range map[string]interface{}{
+		"{}":                            Map{},
+		"{\"a\" 1 \"b\" (+ 1 1)}":       Map{{"a", decimal.New(1, 0)}, {"b", decimal.New(2, 0)}},
+		"{a [a] \"x\" {1 2}}":           Map{{decimal.New(5, 0), Vector{decimal.New(5, 0)}}, {"x", Map{{decimal.New(1, 0), decimal.New(2, 0)}}}},
+		"{nil 1 true 2}":                Map{{nil, decimal.New(1, 0)}, {true, decimal.New(2, 0)}},
+		"(get {\"b\" 2 \"a\" 1} \"a\")": decimal.New(1, 0),
+		"(get {\"a\" 1} \"c\")":         nil,
+		"(get {[1 2] 3} [1 2])":         decimal.New(3, 0),
+	} {
+		readSexp, idx, err := Read(inputForm, 0)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, idx, len(inputForm), inputForm)
+
+		printed := Print(readSexp)
+		require.Equal(t, inputForm, printed)
+
+		evalledSexp, err := Eval(StdEnv, []map[string]interface{}{{"a": decimal.New(5, 0)}}, readSexp)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expectedOutput, evalledSexp), inputForm)
+	}
+}
+
+func TestMapKeyOrder(t *testing.T) {
+	in := "{z 1 a 2 m 3 \"b\" 4 0 5}"
+	sexp := testRead(t, in, Map{{Symbol("z"), decimal.New(1, 0)}, {Symbol("a"), decimal.New(2, 0)}, {Symbol("m"), decimal.New(3, 0)}, {"b", decimal.New(4, 0)}, {decimal.New(0, 0), decimal.New(5, 0)}})
+	for i := 0; i < 10; i++ {
+		require.Equal(t, in, Print(sexp))
+	}
+}
+
+func TestMalformedMaps(t *testing.T) {
+	for in, expectedIdx := range map[string]int{
+		"{a}":               2,
+		"{a 1 b}":           6,
+		"{a 1 a 2}":         5,
+		"{1 a 1.0 b}":       5,
+		"{[1 2] 1 [1 2] 3}": 9,
+		"{a 1":              4,
+		"{a 1)":             4,
+		"(a 1}":             4,
+	} {
+		_, idx, err := Read(in, 0)
+		require.NotNil(t, err, in)
+		require.Equal(t, expectedIdx, idx, in)
+	}
+
+	_, err := ReadEval([]map[string]interface{}{{"a": "x", "b": "x"}}, "{a 1 b 2}")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "duplicate key")
+}
+
+func TestLargeMap(t *testing.T) {
+	n := 20000
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, ":k%d %d ", i, i)
+	}
+	sb.WriteString(":k0 0}")
+	_, err := ReadFully(sb.String())
+	require.Equal(t, DuplicateKey, err.(*ParseError).Kind)
+
+	src := sb.String()[:sb.Len()-len(":k0 0}")] + "}"
+	sexp, err := ReadFully(src)
+	require.Nil(t, err)
+	result, err := Eval(StdEnv, nil, sexp)
+	require.Nil(t, err)
+	require.Equal(t, n, len(result.(Map)))
+	result, err = ReadEval(nil, "`"+src)
+	require.Nil(t, err)
+	require.Equal(t, n, len(result.(Map)))
+}
+
+func TestQuote(t *testing.T) {
+	for inputForm, expectedOutput := range map[string]interface{}{
+		"'a":              Symbol("a"),
+		"'(+ 1 a)":        []interface{}{Symbol("+"), decimal.New(1, 0), Symbol("a")},
+		"''a":             []interface{}{Symbol("quote"), Symbol("a")},
+		"'()":             []interface{}(nil),
+		"'[a (b)]":        Vector{Symbol("a"), []interface{}{Symbol("b")}},
+		"(= 'a 'a)":       true,
+		"(let a 'b a)":    Symbol("b"),
+		"(get {'a 1} 'a)": decimal.New(1, 0),
+		"'\"s\"":          "s",
+	} {
+		readSexp, idx, err := Read(inputForm, 0)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, idx, len(inputForm), inputForm)
+
+		printed := Print(readSexp)
+		require.Equal(t, inputForm, printed)
+
+		evalledSexp, err := Eval(StdEnv, nil, readSexp)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expectedOutput, evalledSexp), inputForm)
+	}
+}
+
+func TestQuoteForms(t *testing.T) {
+	testRead(t, "' a", []interface{}{Symbol("quote"), Symbol("a")})
+	testRead(t, "(quote a)", []interface{}{Symbol("quote"), Symbol("a")})
+	require.Equal(t, "'a", Print([]interface{}{Symbol("quote"), Symbol("a")}))
+	require.Equal(t, "(quote a b)", Print([]interface{}{Symbol("quote"), Symbol("a"), Symbol("b")}))
+
+	evalledSexp, err := ReadEval(nil, "(quote (a b))")
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{Symbol("a"), Symbol("b")}, evalledSexp)
+
+	for _, inputForm := range []string{"(quote)", "(quote a b)"} {
+		_, err := ReadEval(nil, inputForm)
+		require.NotNil(t, err, inputForm)
+	}
+	_, _, err = Read("'", 0)
+	require.NotNil(t, err)
+}
+
+func TestKeywords(t *testing.T) {
+	for inputForm, expectedOutput := range map[string]interface{}{
+		":a":                      Keyword("a"),
+		":amount":                 Keyword("amount"),
+		"(= :a :a)":               true,
+		"(= :a :b)":               false,
+		"(= :a 'a)":               false,
+		"(= :a \"a\")":            false,
+		"(get {:a 1 :b 2} :b)":    decimal.New(2, 0),
+		"(get {\"b\" 1 :b 2} :b)": decimal.New(2, 0),
+		"[:a :b]":                 Vector{Keyword("a"), Keyword("b")},
+		"(let k :x k)":            Keyword("x"),
+		"':a":                     Keyword("a"),
+	} {
+		readSexp, idx, err := Read(inputForm, 0)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, idx, len(inputForm), inputForm)
+
+		printed := Print(readSexp)
+		require.Equal(t, inputForm, printed)
+
+		evalledSexp, err := Eval(StdEnv, nil, readSexp)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expectedOutput, evalledSexp), inputForm)
+	}
+
+	testRead(t, "(:a)", []interface{}{Keyword("a")})
+	_, _, err := Read(":", 0)
+	require.NotNil(t, err)
+	_, _, err = Read("(: a)", 0)
+	require.NotNil(t, err)
+}
+
+func TestReadAll(t *testing.T) {
+	for in, expectedOut := range map[string][]interface{}{
+		"":                        nil,
+		"  \n ; only a comment\n": nil,
+		"(+ 1 2)\n":               {[]interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)}},
+		"a b":                     {Symbol("a"), Symbol("b")},
+		"a \"b\"(c)[d]":           {Symbol("a"), "b", []interface{}{Symbol("c")}, Vector{Symbol("d")}},
+		"; rules\n(a) ; first\n#_(b)\n(c) #| last |#\n": {[]interface{}{Symbol("a")}, []interface{}{Symbol("c")}},
+	} {
+		sexps, err := ReadAll(in)
+		require.Nil(t, err, in)
+		require.Equal(t, expectedOut, sexps, in)
+	}
+
+	for _, in := range []string{"(a) (b", "(a) )", "a #| b", "(a) 1x"} {
+		_, err := ReadAll(in)
+		require.NotNil(t, err, in)
+	}
+}
+
+func TestEvalAll(t *testing.T) {
+	sexps, err := ReadAll("(+ 1 2)\n(get obj :a)\n; done\n")
+	require.Nil(t, err)
+	results, err := EvalAll(StdEnv, []map[string]interface{}{{"obj": Map{{Keyword("a"), "x"}}}}, sexps)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(results))
+	require.Zero(t, decimal.New(3, 0).Cmp(results[0].(decimal.Decimal)))
+	require.Equal(t, "x", results[1])
+
+	_, err = EvalAll(StdEnv, nil, []interface{}{decimal.New(1, 0), Symbol("unbound")})
+	require.NotNil(t, err)
+}
+
+func TestReadFullyTrailingWhitespace(t *testing.T) {
+	for _, in := range []string{"(+ 1 2)\n", "(+ 1 2) ", " (+ 1 2)\r\n\t"} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		require.Equal(t, []interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)}, sexp, in)
+	}
+}
+
+func TestReadPartial(t *testing.T) {
+	for in, expected := range map[string]struct {
+		status InputStatus
+		rest   string
+	}{
+		"(+ 1 2)":                {InputComplete, ""},
+		"(+ 1 2) (- 3":           {InputComplete, " (- 3"},
+		"abc ; comment\n":        {InputComplete, " ; comment\n"},
+		"(+ 1\n":                 {InputIncomplete, "(+ 1\n"},
+		"(let a [1 {:b \"c":      {InputIncomplete, "(let a [1 {:b \"c"},
+		"#| unfinished comment":  {InputIncomplete, "#| unfinished comment"},
+		"'":                      {InputIncomplete, "'"},
+		"(str/":                  {InputIncomplete, "(str/"},
+		"(1_":                    {InputIncomplete, "(1_"},
+		"\"\\ud83d":              {InputIncomplete, "\"\\ud83d"},
+		"\"\\ud83d\\":            {InputIncomplete, "\"\\ud83d\\"},
+		"\"\\ud83d\\ude":         {InputIncomplete, "\"\\ud83d\\ude"},
+		"\"\\ude00":              {InputError, "\\ude00"},
+		"  ; only a comment\n\n": {InputEmpty, "  ; only a comment\n\n"},
+		"":                       {InputEmpty, ""},
+		"(+ 1 2]":                {InputError, "]"},
+		"(+ 1 2x 3)":             {InputError, "x 3)"},
+		")":                      {InputError, ")"},
+		"(str/ x)":               {InputError, "str/ x)"},
+	} {
+		sexp, status, rest, err := ReadPartial(in)
+		require.Equal(t, expected.status, status, in)
+		require.Equal(t, expected.rest, rest, in)
+		require.Equal(t, status == InputError, err != nil, in)
+		require.Equal(t, status == InputComplete, sexp != nil, in)
+	}
+}
+
+func TestReadPartialLineByLine(t *testing.T) {
+	var input string
+	var results []interface{}
+	for _, line := range []string{"(+ 1", "   2)  (* 2", "3) (-", "", "4)"} {
+		input += line + "\n"
+		for {
+			sexp, status, rest, err := ReadPartial(input)
+			require.Nil(t, err, input)
+			if status != InputComplete {
+				break
+			}
+			result, err := Eval(StdEnv, nil, sexp)
+			require.Nil(t, err)
+			results = append(results, result)
+			input = rest
+		}
+	}
+	require.Equal(t, 3, len(results))
+	for i, expected := range []int64{3, 6, -4} {
+		require.True(t, decimal.New(expected, 0).Equal(results[i].(decimal.Decimal)), Print(results[i]))
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	sexps, err := ReadAll("(größe 製品)　[1 2.5] :ärger")
+	require.Nil(t, err)
+	require.Equal(t, 3, len(sexps))
+	require.Equal(t, []interface{}{Symbol("größe"), Symbol("製品")}, sexps[0])
+	require.True(t, equal(Vector{decimal.New(1, 0), decimal.New(25, -1)}, sexps[1]), Print(sexps[1]))
+	require.Equal(t, Keyword("ärger"), sexps[2])
+
+	_, _, err = Read("(größe\n  製品 1ü)", 0)
+	parseErr, ok := err.(*ParseError)
+	require.True(t, ok)
+	require.Equal(t, BadNumber, parseErr.Kind)
+	require.Equal(t, Position{19, 2, 7, 13}, parseErr.Position())
+	require.Equal(t, "'ü'", parseErr.Found)
+	require.Equal(t, "2:7: not a valid number, unexpected character 'ü': 1ü", err.Error())
+
+	_, _, err = Read("(a b\xff)", 0)
+	parseErr, ok = err.(*ParseError)
+	require.True(t, ok)
+	require.Equal(t, UnexpectedChar, parseErr.Kind)
+	require.Equal(t, 4, parseErr.Offset)
+
+	// strings keep their bytes
+	sexp, _, err := Read("\"a\xffb\"", 0)
+	require.Nil(t, err)
+	require.Equal(t, "a\xffb", sexp)
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	// a stand-in for norm.NFC.String, composing "a" followed by U+0308 COMBINING DIAERESIS to U+00E4
+	nfc := func(s string) string { return strings.Replace(s, "a\u0308", "\u00e4", -1) }
+	sexp, _, err := ReadWithOptions("(sp\u00e4t spa\u0308t :a\u0308 \"a\u0308\")", 0, ReaderOptions{NormalizeSymbol: nfc})
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{Symbol("sp\u00e4t"), Symbol("sp\u00e4t"), Keyword("\u00e4"), "a\u0308"}, sexp)
+
+	sexp, _, err = Read("spa\u0308t", 0)
+	require.Nil(t, err)
+	require.Equal(t, Symbol("spa\u0308t"), sexp)
+}
+
+func TestReaderLimits(t *testing.T) {
+	deep := strings.Repeat("(", 100000) + strings.Repeat(")", 100000)
+	for in, tc := range map[string]struct {
+		opts           ReaderOptions
+		expectedOffset int
+	}{
+		deep:                   {ReaderOptions{MaxDepth: 100}, 100},
+		"[1 '(2 {:a [3]})]":    {ReaderOptions{MaxDepth: 4}, 11},
+		"[1 #inst \"x\"]":      {ReaderOptions{MaxDepth: 1}, 3},
+		"(+ 1 2 3)":            {ReaderOptions{MaxForms: 4}, 7},
+		"(a #_ b c)":           {ReaderOptions{MaxForms: 3}, 8},
+		"(a \"abc\" \"abcd\")": {ReaderOptions{MaxStringLength: 3}, 9},
+		"\"\\u00e4\\u00e4\"":   {ReaderOptions{MaxStringLength: 3}, 0},
+		"(a b c)":              {ReaderOptions{MaxInputBytes: 6}, 6},
+		"(a b c)    ":          {ReaderOptions{MaxInputBytes: 10}, 10},
+	} {
+		_, _, err := ReadWithOptions(in, 0, tc.opts)
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, LimitExceeded, parseErr.Kind, in)
+		require.Equal(t, tc.expectedOffset, parseErr.Offset, in)
+	}
+
+	for in, opts := range map[string]ReaderOptions{
+		"[1 '(2 {:a [3]})]": {MaxDepth: 5},
+		"(+ 1 2 3)":         {MaxForms: 5},
+		"(a \"abc\")":       {MaxStringLength: 3},
+		"(a b c)":           {MaxInputBytes: 7},
+	} {
+		_, _, err := ReadWithOptions(in, 0, opts)
+		require.Nil(t, err, in)
+	}
+
+	// MaxForms counts all forms read
+	_, err := ReadAllWithOptions("(a b) (c d)", ReaderOptions{MaxForms: 5})
+	parseErr, ok := err.(*ParseError)
+	require.True(t, ok)
+	require.Equal(t, LimitExceeded, parseErr.Kind)
+	require.Equal(t, "1:10: more forms than MaxForms (5)\n(a b) (c d)\n         ^", err.Error())
+
+	// long runs of #_ do not exhaust the stack
+	n := 3000000
+	sexp, _, err := ReadWithOptions(strings.Repeat("#_", n)+strings.Repeat(" a", n)+" b", 0, ReaderOptions{MaxDepth: 100})
+	require.Nil(t, err)
+	require.Equal(t, Symbol("b"), sexp)
+	_, _, err = ReadWithOptions(strings.Repeat("#_", n)+strings.Repeat(" a", n-1), 0, ReaderOptions{MaxDepth: 100})
+	require.Equal(t, Incomplete, err.(*ParseError).Kind)
+}
+
+func TestNamespacedSymbols(t *testing.T) {
+	for in, expected := range map[string][2]string{
+		"str/join":              {"str", "join"},
+		"acme.pricing/discount": {"acme.pricing", "discount"},
+		"join":                  {"", "join"},
+		"/":                     {"", "/"},
+		"ns//":                  {"ns", "/"},
+		"a/b/c":                 {"a", "b/c"},
+	} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		require.Equal(t, Symbol(in), sexp, in)
+		require.Equal(t, expected[0], sexp.(Symbol).Namespace(), in)
+		require.Equal(t, expected[1], sexp.(Symbol).Name(), in)
+	}
+
+	for _, in := range []string{"a/", "/a", "(b /a)"} {
+		_, err := ReadFully(in)
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, UnexpectedChar, parseErr.Kind, in)
+	}
+
+	env := map[string]interface{}{
+		"discount": "flat discount",
+		"acme.pricing": Namespace{
+			"discount": func(args []interface{}) (interface{}, error) {
+				return args[0].(decimal.Decimal).Mul(decimal.New(9, -1)), nil
+			},
+			"rate": decimal.New(5, -2),
+		},
+		"acme.pricing/rate": decimal.New(1, -1),
+		"+":                 StdEnv["+"],
+	}
+	for in, expected := range map[string]interface{}{
+		"(acme.pricing/discount 10)": decimal.New(9, 0),
+		"discount":                   "flat discount",
+		"acme.pricing/rate":          decimal.New(1, -1),
+	} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		result, err := Eval(env, nil, sexp)
+		require.Nil(t, err, in)
+		require.True(t, equal(expected, result), in)
+	}
+
+	// StdEnv does not bind the namespaces, so the namespaced names do not resolve in it
+	for _, in := range []string{"(acme.pricing/discount 10)", "acme.pricing/rate"} {
+		_, err := ReadEval(nil, in)
+		require.NotNil(t, err, in)
+	}
+
+	// namespaces can also be bound in the lexical scope
+	sexp, _ := ReadFully("(+ rates/vat 1)")
+	result, err := Eval(env, []map[string]interface{}{{"rates": Namespace{"vat": decimal.New(19, -2)}}}, sexp)
+	require.Nil(t, err)
+	require.True(t, equal(decimal.New(119, -2), result))
+
+	_, err = Eval(env, nil, Symbol("acme.pricing/unknown"))
+	require.EqualError(t, err, "Unbound name acme.pricing/unknown")
+}
@@ -0,0 +1,322 @@
+package minsexp
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// NodeKind tells what kind of form a Node is
+type NodeKind int
+
+const (
+	// AtomNode is a symbol, keyword, number, string or any other form that does not contain forms
+	AtomNode NodeKind = iota
+	ListNode
+	VectorNode
+	MapNode
+	// PrefixedNode is a form preceded by a prefix, like 'x, ~@xs or #inst "2024-05-01T00:00:00Z"
+	PrefixedNode
+)
+
+// Node is a form in a concrete syntax tree. Unlike the forms returned by Read, nodes keep the source text they were
+// read from, including whitespace and comments, so that printing a node yields that text byte for byte
+type Node struct {
+	Kind NodeKind
+	// Leading is the whitespace and comments, including forms discarded using #_, preceding the node
+	Leading string
+	// Text is the source text of an atom, like 1.50 or "a\tb", the prefix of a prefixed form, like ' or #inst,
+	// or the opening delimiter of a list, vector or map
+	Text string
+	// Value is the form an atom was read as, e.g. the decimal.Decimal 1.5 for the Text 1.50
+	Value interface{}
+	// Children are the elements of a list or vector, the keys and values of a map, or the form following a prefix
+	Children []*Node
+	// Closing is the whitespace and comments preceding the closing delimiter of a list, vector or map,
+	// followed by the delimiter
+	Closing string
+	// Span is where the node, excluding Leading, was read from
+	Span Span
+
+	prefix Symbol      // the symbol a prefixed form is read as a list with, like quote for '
+	macro  ReaderMacro // the reader macro a prefixed form is read with, unless prefix is set
+}
+
+// CST is the concrete syntax tree of a text, see Node
+type CST struct {
+	Nodes []*Node
+	// Trailing is the whitespace and comments following the last node
+	Trailing string
+}
+
+// ReadCST reads all forms in sexpStr like ReadAllWithOptions does, as a concrete syntax tree.
+// Reader macros, like those of tagged literals, are not called, and maps are not checked for duplicate keys,
+// until the nodes are converted to forms. Forms discarded using #_ are not converted at all
+func ReadCST(sexpStr string, opts ReaderOptions) (cst *CST, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			cst = nil
+			var ok bool
+			err, ok = rec.(error)
+			if !ok {
+				err = fmt.Errorf("minsexp: %v", rec)
+			}
+			err = errors.WithStack(err)
+		}
+	}()
+	r := newReader(sexpStr, opts, startPosition)
+	r.cst = true
+	cst = &CST{}
+	idx := 0
+	for {
+		nextIdx, err := r.getNextNonWSP(idx)
+		if err != nil {
+			return nil, r.annotate(err, nextIdx)
+		}
+		if nextIdx == len(sexpStr) {
+			cst.Trailing = sexpStr[idx:]
+			return cst, nil
+		}
+		var node *Node
+		node, idx, err = r.parseNode(idx)
+		if err != nil {
+			return nil, r.annotate(err, idx)
+		}
+		cst.Nodes = append(cst.Nodes, node)
+	}
+}
+
+// parseNode parses the node following startIdx, along with the whitespace and comments preceding it
+func (r *reader) parseNode(startIdx int) (*Node, int, error) {
+	s := r.s
+	i, err := r.getNextNonWSP(startIdx)
+	if err != nil {
+		return nil, i, err
+	}
+	if i >= len(s) {
+		return nil, i, parseError(Incomplete, i, "reached end of input parsing sexp").expecting("sexp", s, i)
+	}
+	r.forms++
+	if max := r.opts.MaxForms; max > 0 && r.forms > max {
+		return nil, i, parseError(LimitExceeded, i, fmt.Sprintf("more forms than MaxForms (%d)", max))
+	}
+	node := &Node{Leading: s[startIdx:i]}
+	var idx int
+	switch s[i] {
+	case '(':
+		node.Kind = ListNode
+		idx, err = r.parseSeqNode(node, i, ')', "list")
+	case '[':
+		node.Kind = VectorNode
+		idx, err = r.parseSeqNode(node, i, ']', "vector")
+	case '{':
+		node.Kind = MapNode
+		idx, err = r.parseSeqNode(node, i, '}', "map")
+	default:
+		if prefixLen := r.prefixNode(node, i); prefixLen > 0 {
+			idx, err = r.parsePrefixedNode(node, i, prefixLen)
+		} else {
+			node.Kind = AtomNode
+			node.Value, idx, err = r.parseForm(i)
+			node.Text = s[i:idx]
+		}
+	}
+	if err != nil {
+		return nil, idx, err
+	}
+	node.Span = r.source().span(i, idx)
+	return node, idx, nil
+}
+
+// prefixNode sets the prefix or reader macro of node and returns the length of the prefix starting at idx,
+// or 0 if there is none
+func (r *reader) prefixNode(node *Node, idx int) int {
+	s := r.s
+	if rt := r.opts.Readtable; rt != nil {
+		if macro, prefixLen, ok := rt.prefixMacro(s, idx); ok {
+			node.macro = macro
+			return prefixLen
+		}
+	}
+	switch s[idx] {
+	case '#':
+		if macro, prefixLen, ok := r.dispatchMacro(idx); ok {
+			node.macro = macro
+			return prefixLen
+		}
+	case '\'':
+		node.prefix = Symbol("quote")
+		return 1
+	case '`':
+		node.prefix = Symbol("quasiquote")
+		return 1
+	case '~':
+		if idx+1 < len(s) && s[idx+1] == '@' {
+			node.prefix = Symbol("unquote-splicing")
+			return 2
+		}
+		node.prefix = Symbol("unquote")
+		return 1
+	}
+	return 0
+}
+
+func (r *reader) parsePrefixedNode(node *Node, startIdx int, prefixLen int) (int, error) {
+	defer r.leave()
+	if err := r.enter(startIdx); err != nil {
+		return startIdx, err
+	}
+	node.Kind = PrefixedNode
+	node.Text = r.s[startIdx : startIdx+prefixLen]
+	child, idx, err := r.parseNode(startIdx + prefixLen)
+	if err != nil {
+		return idx, err
+	}
+	node.Children = []*Node{child}
+	return idx, nil
+}
+
+func (r *reader) parseSeqNode(node *Node, startIdx int, closing byte, kind string) (int, error) {
+	defer r.leave()
+	if err := r.enter(startIdx); err != nil {
+		return startIdx, err
+	}
+	s := r.s
+	node.Text = s[startIdx : startIdx+1]
+	idx := startIdx + 1
+	for {
+		i, err := r.getNextNonWSP(idx)
+		if err != nil {
+			return i, err
+		}
+		if i >= len(s) {
+			msg := fmt.Sprintf("reached end of input parsing %v starting at %v", kind, r.source().position(startIdx))
+			return i, parseError(Incomplete, i, msg).expecting(fmt.Sprintf("'%c'", closing), s, i)
+		}
+		switch s[i] {
+		case closing:
+			if closing == '}' && len(node.Children)%2 != 0 {
+				return i, parseError(UnexpectedChar, i, "map needs an even number of forms: key/value pairs").expecting("value", s, i)
+			}
+			node.Closing = s[idx : i+1]
+			return i + 1, nil
+		case ')', ']', '}':
+			msg := fmt.Sprintf("expected '%c' to close %v starting at %v, but got '%c'", closing, kind, r.source().position(startIdx), s[i])
+			return i, parseError(UnbalancedParen, i, msg).expecting(fmt.Sprintf("'%c'", closing), s, i)
+		}
+		var child *Node
+		child, idx, err = r.parseNode(idx)
+		if err != nil {
+			return idx, err
+		}
+		node.Children = append(node.Children, child)
+	}
+}
+
+// String returns the source text of the node, including Leading
+func (n *Node) String() string {
+	var sb strings.Builder
+	n.writeTo(&sb)
+	return sb.String()
+}
+
+func (n *Node) writeTo(sb *strings.Builder) {
+	sb.WriteString(n.Leading)
+	sb.WriteString(n.Text)
+	for _, child := range n.Children {
+		child.writeTo(sb)
+	}
+	sb.WriteString(n.Closing)
+}
+
+// String returns the text the CST was read from
+func (cst *CST) String() string {
+	var sb strings.Builder
+	for _, node := range cst.Nodes {
+		node.writeTo(&sb)
+	}
+	sb.WriteString(cst.Trailing)
+	return sb.String()
+}
+
+// Form returns the form the node is read as by Read
+func (n *Node) Form() (form interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			form = nil
+			var ok bool
+			err, ok = rec.(error)
+			if !ok {
+				err = fmt.Errorf("minsexp: %v", rec)
+			}
+			err = errors.WithStack(err)
+		}
+	}()
+	return n.form()
+}
+
+func (n *Node) form() (interface{}, error) {
+	switch n.Kind {
+	case AtomNode:
+		return n.Value, nil
+	case PrefixedNode:
+		form, err := n.Children[0].form()
+		if err != nil {
+			return nil, err
+		}
+		if n.macro != nil {
+			result, err := n.macro(form)
+			if err != nil {
+				parseErr := n.error(ReaderMacroFailed, fmt.Sprintf("%v: %v", n.Text, err))
+				parseErr.Err = err
+				return nil, parseErr
+			}
+			return result, nil
+		}
+		return []interface{}{n.prefix, form}, nil
+	}
+	elems := make([]interface{}, len(n.Children))
+	for i, child := range n.Children {
+		elem, err := child.form()
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = elem
+	}
+	switch n.Kind {
+	case ListNode:
+		return elems, nil
+	case VectorNode:
+		return Vector(elems), nil
+	}
+	if len(elems)%2 != 0 {
+		return nil, n.error(UnexpectedChar, "map needs an even number of forms: key/value pairs")
+	}
+	m := make(Map, 0, len(elems)/2)
+	keys := newMapKeys(len(elems) / 2)
+	for i := 0; i < len(elems); i += 2 {
+		if !keys.add(elems[i]) {
+			return nil, n.Children[i].error(DuplicateKey, "duplicate key in map: "+Print(elems[i]))
+		}
+		m = append(m, MapEntry{elems[i], elems[i+1]})
+	}
+	return m, nil
+}
+
+func (n *Node) error(kind ParseErrorKind, msg string) *ParseError {
+	pos := n.Span.Start
+	return &ParseError{Kind: kind, Msg: msg, Offset: pos.Offset, Line: pos.Line, Column: pos.Column, RuneOffset: pos.RuneOffset}
+}
+
+// Forms returns the forms the nodes of the CST are read as by ReadAll
+func (cst *CST) Forms() ([]interface{}, error) {
+	forms := make([]interface{}, len(cst.Nodes))
+	for i, node := range cst.Nodes {
+		form, err := node.Form()
+		if err != nil {
+			return nil, err
+		}
+		forms[i] = form
+	}
+	return forms, nil
+}
@@ -0,0 +1,131 @@
+package minsexp
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCSTRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"  ; only a comment\n",
+		"(+ 1.50 0x1F 1_000 -2.5e3)",
+		"; rules\n(let price 12.50 ; in EUR\n  #_ (ignored 1)\n  #| block #| nested |# |#\n  [price\t{:a \"\\u00e4\\n\" :b 'c}]  )\n\n(d) ",
+		"`(a ~b ~@ c)",
+		"#inst \"2024-05-01T00:00:00Z\" #uuid   \"f81d4fae-7dec-11d0-a765-00a0c91e6bf6\"",
+		"( größe　製品 )",
+	} {
+		cst, err := ReadCST(src, ReaderOptions{})
+		require.Nil(t, err, src)
+		require.Equal(t, src, cst.String(), src)
+
+		forms, err := cst.Forms()
+		require.Nil(t, err, src)
+		expected, err := ReadAll(src)
+		require.Nil(t, err, src)
+		require.Equal(t, len(expected), len(forms), src)
+		for i := range forms {
+			require.True(t, equal(expected[i], forms[i]), src)
+		}
+	}
+}
+
+func TestCSTNodes(t *testing.T) {
+	src := "; rules\n(let price 1.50 ; in EUR\n  'x)\n"
+	cst, err := ReadCST(src, ReaderOptions{})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(cst.Nodes))
+	require.Equal(t, "\n", cst.Trailing)
+
+	list := cst.Nodes[0]
+	require.Equal(t, ListNode, list.Kind)
+	require.Equal(t, "; rules\n", list.Leading)
+	require.Equal(t, "(", list.Text)
+	require.Equal(t, ")", list.Closing)
+	require.Equal(t, Span{Position{8, 2, 1, 8}, Position{38, 3, 6, 38}}, list.Span)
+	require.Equal(t, 4, len(list.Children))
+
+	price := list.Children[2]
+	require.Equal(t, AtomNode, price.Kind)
+	require.Equal(t, " ", price.Leading)
+	require.Equal(t, "1.50", price.Text)
+	require.True(t, decimal.New(15, -1).Equal(price.Value.(decimal.Decimal)))
+
+	quoted := list.Children[3]
+	require.Equal(t, PrefixedNode, quoted.Kind)
+	require.Equal(t, " ; in EUR\n  ", quoted.Leading)
+	require.Equal(t, "'", quoted.Text)
+	require.Equal(t, "x", quoted.Children[0].Text)
+	form, err := quoted.Form()
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{Symbol("quote"), Symbol("x")}, form)
+
+	// editing the text of a node
+	price.Text = "1.75"
+	require.Equal(t, "; rules\n(let price 1.75 ; in EUR\n  'x)\n", cst.String())
+}
+
+func TestCSTErrors(t *testing.T) {
+	for in, kind := range map[string]ParseErrorKind{
+		"(a\n b":       Incomplete,
+		"(a ]":         UnbalancedParen,
+		"{:a 1 :b}":    UnexpectedChar,
+		"(a 1x)":       BadNumber,
+		"(a #| b":      Incomplete,
+		"[1 \"a\\q\"]": UnexpectedChar,
+	} {
+		_, err := ReadCST(in, ReaderOptions{})
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, kind, parseErr.Kind, in)
+	}
+
+	_, err := ReadCST("(((a)))", ReaderOptions{MaxDepth: 2})
+	require.Equal(t, LimitExceeded, err.(*ParseError).Kind)
+
+	// reader macros are called and duplicate keys detected when converting to forms
+	for in, kind := range map[string]ParseErrorKind{
+		"(a\n  #inst \"x\")": ReaderMacroFailed,
+		"(a\n  {:b 1 :b 2})": DuplicateKey,
+	} {
+		cst, err := ReadCST(in, ReaderOptions{})
+		require.Nil(t, err, in)
+		_, err = cst.Forms()
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, kind, parseErr.Kind, in)
+		require.Equal(t, 2, parseErr.Line, in)
+	}
+}
+
+func TestCSTMacros(t *testing.T) {
+	rt := NewReadtable()
+	rt.SetDispatch("boom", func(form interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	opts := ReaderOptions{Readtable: rt}
+
+	// macros of discarded forms are never called
+	for _, src := range []string{"#_ #boom 1 a", "#_ #inst \"x\" a", "(#_ #_ #boom 1 #inst \"y\" a)"} {
+		cst, err := ReadCST(src, opts)
+		require.Nil(t, err, src)
+		require.Equal(t, src, cst.String(), src)
+		_, err = cst.Forms()
+		require.Nil(t, err, src)
+	}
+
+	// nor are they when reading other forms
+	cst, err := ReadCST("[#boom 1]", opts)
+	require.Nil(t, err)
+	require.Equal(t, "#boom", cst.Nodes[0].Children[0].Text)
+
+	// panics are returned as errors, like by ReadWithOptions
+	_, err = cst.Forms()
+	require.EqualError(t, err, "minsexp: boom")
+	opts.NormalizeSymbol = func(name string) string {
+		panic("cannot normalize " + name)
+	}
+	_, err = ReadCST("#_ a", opts)
+	require.EqualError(t, err, "minsexp: cannot normalize a")
+}
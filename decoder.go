@@ -0,0 +1,178 @@
+package minsexp
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const decoderChunkSize = 4096
+
+// Decoder reads successive top-level forms from an io.Reader
+type Decoder struct {
+	r     io.Reader
+	opts  ReaderOptions
+	text  string   // the text read but not yet consumed by Decode
+	base  Position // the position of text[0]
+	eof   bool
+	err   error
+	forms int             // the number of forms read so far, checked against MaxForms
+	src   *source         // the text consumed so far, registered in the SourceMap if there is one
+	line  strings.Builder // the text consumed since the last newline, if there is no SourceMap to take it from
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderWithOptions(r, ReaderOptions{})
+}
+
+// NewDecoderWithOptions returns a Decoder which reads forms like ReadWithOptions does.
+// Positions in errors and in the SourceMap are relative to the start of the input
+func NewDecoderWithOptions(r io.Reader, opts ReaderOptions) *Decoder {
+	d := &Decoder{r: r, opts: opts, base: startPosition}
+	if sm := opts.SourceMap; sm != nil {
+		// a single source growing with the input, instead of one per form
+		d.src = newSource(opts.File, "", startPosition)
+		sm.srcs = append(sm.srcs, d.src)
+	}
+	return d
+}
+
+// Decode returns the next form, or io.EOF if there are no more forms
+func (d *Decoder) Decode() (interface{}, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	for {
+		// a character split across chunks would be mistaken for an invalid one
+		if !d.eof && endsWithPartialRune(d.text) {
+			if d.err = d.fill(); d.err != nil {
+				return nil, d.err
+			}
+			continue
+		}
+		r := &reader{s: d.text, opts: d.opts, base: d.base, forms: d.forms}
+		if d.src != nil {
+			// spans are recorded from the text not yet consumed, which is only added to d.src once consumed
+			r.src = newSource(d.opts.File, d.text, d.base)
+		}
+		sexp, idx, found, err := r.readNext(0)
+		// a form ending at the end of the text might continue in the next chunk, e.g. a symbol
+		if !d.eof && ((err == nil && idx == len(d.text) && (!found || mayExtend(d.text))) || (err != nil && mayContinue(d.text, idx, err))) {
+			if d.err = d.fill(); d.err != nil {
+				return nil, d.err
+			}
+			continue
+		}
+		if err != nil {
+			d.err = d.annotate(err, idx)
+			return nil, d.err
+		}
+		d.consume(idx)
+		d.forms = r.forms
+		if !found {
+			return nil, io.EOF
+		}
+		return sexp, nil
+	}
+}
+
+// Position returns the position after the last form read
+func (d *Decoder) Position() Position {
+	return d.base
+}
+
+// fill appends the next chunk of input to the text. Chunks grow with the text, so that a form spanning many chunks
+// is not copied and read again once per chunk
+func (d *Decoder) fill() error {
+	chunkSize := decoderChunkSize
+	if len(d.text) > chunkSize {
+		chunkSize = len(d.text)
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := d.r.Read(buf)
+		d.text += string(buf[:n])
+		if err == io.EOF {
+			d.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+	}
+}
+
+// mayContinue reports whether the error found at idx might go away once more input is read, because it was found
+// at the end of the text or in its last token, like the "str/" of a "str/join" split across chunks
+func mayContinue(text string, idx int, err error) bool {
+	parseErr, ok := err.(*ParseError)
+	if !ok || parseErr.Kind == LimitExceeded {
+		return false
+	}
+	return parseErr.atEnd || idx >= strings.LastIndexAny(text, " \t\r\n()[]{}")+1
+}
+
+// mayExtend reports whether a form read up to the end of text might continue in more input, which is the case
+// for symbols, numbers and keywords, but not for forms ending in a closing bracket or double quote. Those are
+// returned at once, instead of waiting for more input that might only arrive with the next form
+func mayExtend(text string) bool {
+	switch text[len(text)-1] {
+	case ')', ']', '}', '"':
+		return false
+	}
+	return true
+}
+
+func endsWithPartialRune(s string) bool {
+	for i := len(s) - 1; i >= 0 && i >= len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			return !utf8.FullRuneInString(s[i:])
+		}
+	}
+	return false
+}
+
+// annotate is like reader.annotate, but shows the whole line of the error in the snippet, instead of only the part
+// of it not yet consumed
+func (d *Decoder) annotate(err error, idx int) error {
+	var linePrefix string
+	if d.src != nil {
+		linePrefix = d.src.text[strings.LastIndexByte(d.src.text, '\n')+1:]
+	} else {
+		linePrefix = d.line.String()
+	}
+	lineStart := Position{
+		d.base.Offset - len(linePrefix), d.base.Line, 1, d.base.RuneOffset - utf8.RuneCountInString(linePrefix),
+	}
+	r := &reader{s: linePrefix + d.text, opts: d.opts, base: lineStart}
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.idx += len(linePrefix)
+	}
+	return r.annotate(err, len(linePrefix)+idx)
+}
+
+// consume drops the text before idx, advancing the position of the text past it
+func (d *Decoder) consume(idx int) {
+	consumed := d.text[:idx]
+	runeCount := utf8.RuneCountInString(consumed)
+	if lineStartIdx := strings.LastIndexByte(consumed, '\n') + 1; lineStartIdx > 0 {
+		d.base.Line += strings.Count(consumed, "\n")
+		d.base.Column = utf8.RuneCountInString(consumed[lineStartIdx:]) + 1
+	} else {
+		d.base.Column += runeCount
+	}
+	d.base.Offset += idx
+	d.base.RuneOffset += runeCount
+	if d.src != nil {
+		d.src.extend(consumed)
+	} else if lineStartIdx := strings.LastIndexByte(consumed, '\n') + 1; lineStartIdx > 0 {
+		d.line.Reset()
+		d.line.WriteString(consumed[lineStartIdx:])
+	} else {
+		d.line.WriteString(consumed)
+	}
+	d.text = d.text[idx:]
+}
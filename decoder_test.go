@@ -0,0 +1,247 @@
+package minsexp
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	src := "(+ 1 2)\n; comment\nabc \"a string\" #| block |#\n[1 2.5e1] {:a 0x10}#_ignored\n'x 12345"
+	expectedForms := []interface{}{
+		[]interface{}{Symbol("+"), decimal.New(1, 0), decimal.New(2, 0)},
+		Symbol("abc"),
+		"a string",
+		Vector{decimal.New(1, 0), decimal.New(25, 0)},
+		Map{{Keyword("a"), decimal.New(16, 0)}},
+		[]interface{}{Symbol("quote"), Symbol("x")},
+		decimal.New(12345, 0),
+	}
+	for _, r := range []io.Reader{strings.NewReader(src), iotest.OneByteReader(strings.NewReader(src)), iotest.DataErrReader(iotest.HalfReader(strings.NewReader(src)))} {
+		d := NewDecoder(r)
+		var forms []interface{}
+		for {
+			form, err := d.Decode()
+			if err == io.EOF {
+				break
+			}
+			require.Nil(t, err)
+			forms = append(forms, form)
+		}
+		require.Equal(t, len(expectedForms), len(forms))
+		for i := range forms {
+			require.True(t, equal(expectedForms[i], forms[i]), Print(forms[i]))
+		}
+		_, err := d.Decode()
+		require.Equal(t, io.EOF, err)
+	}
+}
+
+func TestDecoderPositions(t *testing.T) {
+	src := "(a)\n(b\n  (c d))\n\n  (e (f g))"
+	sm := &SourceMap{}
+	d := NewDecoderWithOptions(iotest.OneByteReader(strings.NewReader(src)), ReaderOptions{File: "rules.sexp", SourceMap: sm})
+
+	var forms []interface{}
+	for {
+		form, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		forms = append(forms, form)
+	}
+	require.Equal(t, 3, len(forms))
+
+	span, ok := sm.Span(forms[1])
+	require.True(t, ok)
+	require.Equal(t, Span{Position{4, 2, 1, 4}, Position{15, 3, 9, 15}}, span)
+
+	span, ok = sm.ElemSpan(forms[2].([]interface{}), 1)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{22, 5, 6, 22}, Position{27, 5, 11, 27}}, span)
+	require.Equal(t, Position{28, 5, 12, 28}, d.Position())
+
+	_, err := sm.Eval(StdEnv, nil, forms[2])
+	require.NotNil(t, err)
+	require.Equal(t, "rules.sexp:5:4: Unbound name e\n  (e (f g))\n   ^", err.Error())
+}
+
+func TestDecoderSourceMap(t *testing.T) {
+	n := 10000
+	src := strings.Repeat("(+ 1)\n", n) + "(+ 2\n  (x 3))"
+	sm := &SourceMap{}
+	d := NewDecoderWithOptions(strings.NewReader(src), ReaderOptions{File: "rules.sexp", SourceMap: sm})
+	var forms []interface{}
+	for {
+		form, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		forms = append(forms, form)
+	}
+	require.Equal(t, n+1, len(forms))
+	// the forms share a single source, holding the text read
+	require.Equal(t, 1, len(sm.srcs))
+	require.Equal(t, src, sm.srcs[0].text)
+
+	_, err := sm.Eval(StdEnv, nil, forms[n])
+	require.NotNil(t, err)
+	require.Equal(t, "rules.sexp:10002:4: Unbound name x\n  (x 3))\n   ^", err.Error())
+}
+
+func TestDecoderErrors(t *testing.T) {
+	for src, expectedErr := range map[string]string{
+		"(a)\n(b\n  (c d]":    "rules.sexp:3:7: expected ')' to close list starting at 3:3, but got ']'\n  (c d]\n      ^",
+		"(a)\n(b\n  (c d)":    "rules.sexp:3:8: reached end of input parsing list starting at 2:1\n  (c d)\n       ^",
+		"(a) (b)\n\"abc":      "rules.sexp:2:5: string not terminated by double quote\n\"abc\n    ^",
+		"(a)\n  1x":           "rules.sexp:2:4: not a valid number, unexpected character 'x': 1x\n  1x\n   ^",
+		"(a)\n#| comment\n\n": "rules.sexp:4:1: block comment not terminated by |#\n\n^",
+	} {
+		d := NewDecoderWithOptions(iotest.OneByteReader(strings.NewReader(src)), ReaderOptions{File: "rules.sexp"})
+		var err error
+		for err == nil {
+			_, err = d.Decode()
+		}
+		require.NotEqual(t, io.EOF, err, src)
+		require.Equal(t, expectedErr, err.Error(), src)
+		_, err2 := d.Decode()
+		require.Equal(t, err, err2)
+	}
+}
+
+func TestDecoderUnicode(t *testing.T) {
+	src := "(größe 1)　製品\n 「値」 2 "
+	d := NewDecoder(iotest.OneByteReader(strings.NewReader(src)))
+	var forms []interface{}
+	for {
+		form, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		forms = append(forms, form)
+	}
+	require.Equal(t, 4, len(forms))
+	require.Equal(t, Symbol("製品"), forms[1])
+	require.Equal(t, Symbol("「値」"), forms[2])
+	require.Equal(t, Position{len(src), 2, 8, 20}, d.Position())
+}
+
+func TestDecoderLimits(t *testing.T) {
+	for _, opts := range []ReaderOptions{{MaxForms: 4}, {MaxInputBytes: 12}, {MaxDepth: 1}} {
+		d := NewDecoderWithOptions(iotest.OneByteReader(strings.NewReader("(a b)\n(c (d))\n(e f)")), opts)
+		_, err := d.Decode()
+		require.Nil(t, err)
+		_, err = d.Decode()
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok)
+		require.Equal(t, LimitExceeded, parseErr.Kind)
+		require.Equal(t, 2, parseErr.Line)
+	}
+}
+
+func TestDecoderLongLine(t *testing.T) {
+	n := 40000
+	src := strings.Repeat("(a b c) ", n) + "(d é]"
+	d := NewDecoderWithOptions(strings.NewReader(src), ReaderOptions{File: "rules.sexp"})
+	for i := 0; i < n; i++ {
+		_, err := d.Decode()
+		require.Nil(t, err)
+		require.Equal(t, Position{8*i + 7, 1, 8*i + 8, 8*i + 7}, d.Position())
+		// the text consumed is dropped, even though there is no newline
+		require.True(t, len(d.text) <= 2*decoderChunkSize)
+	}
+	_, err := d.Decode()
+	// the snippet shows the whole line, even though most of it has been consumed
+	require.Equal(t, "rules.sexp:1:320005: expected ')' to close list starting at 1:320001, but got ']'\n"+
+		src+"\n"+strings.Repeat(" ", 8*n+4)+"^", err.Error())
+
+	// the consumed text is taken from the SourceMap, if there is one
+	d = NewDecoderWithOptions(strings.NewReader("(a b)\n(c d) (e é]"), ReaderOptions{SourceMap: &SourceMap{}})
+	for i := 0; i < 2; i++ {
+		_, err = d.Decode()
+		require.Nil(t, err)
+	}
+	_, err = d.Decode()
+	require.Equal(t, "2:11: expected ')' to close list starting at 2:7, but got ']'\n(c d) (e é]\n          ^", err.Error())
+
+	// a form spanning many chunks
+	d = NewDecoder(iotest.HalfReader(strings.NewReader("[" + strings.Repeat("1 ", n) + "]")))
+	form, err := d.Decode()
+	require.Nil(t, err)
+	require.Equal(t, n, len(form.(Vector)))
+	require.Equal(t, Position{2*n + 2, 1, 2*n + 3, 2*n + 2}, d.Position())
+}
+
+func TestDecoderChunkBoundaries(t *testing.T) {
+	for _, tail := range []string{
+		"symbol", "str/join", "ns//", ":keyword", "größe", "1_000", "-1.5e-3", "0x1F", "0b1_01", "\"a\\nb\\u00e4\"",
+		"\"\\ud83d\\ude00\"", "#\"\\d+\"", "#inst \"2024-05-01T00:00:00Z\"", "#| block |#1", "#_ignored 1", "##-Inf", "(a [b {:c 1}])",
+	} {
+		expected, err := ReadAll(tail)
+		require.Nil(t, err, tail)
+		// the first chunk ends after k bytes of the tail
+		for k := 1; k < len(tail); k++ {
+			src := strings.Repeat(" ", decoderChunkSize-k) + tail
+			d := NewDecoder(strings.NewReader(src))
+			var forms []interface{}
+			for {
+				form, err := d.Decode()
+				if err == io.EOF {
+					break
+				}
+				require.Nil(t, err, "%q split after %d bytes", tail, k)
+				forms = append(forms, form)
+			}
+			require.True(t, equal(expected, forms), "%q split after %d bytes", tail, k)
+		}
+	}
+}
+
+func TestDecoderPipe(t *testing.T) {
+	pr, pw := io.Pipe()
+	d := NewDecoder(pr)
+	forms := make(chan interface{})
+	go func() {
+		for {
+			form, err := d.Decode()
+			if err != nil {
+				close(forms)
+				return
+			}
+			forms <- form
+		}
+	}()
+	next := func() interface{} {
+		select {
+		case form := <-forms:
+			return form
+		case <-time.After(10 * time.Second):
+			t.Fatal("Decode is waiting for more input")
+			return nil
+		}
+	}
+	// forms ending in a closing bracket or double quote are returned without waiting for more input
+	for _, in := range []string{`(a)`, `"s"`, `[1 2]`, `{:a 1}`, `#"\d+"`, `#inst "2024-05-01T00:00:00Z"`} {
+		_, err := pw.Write([]byte(in))
+		require.Nil(t, err)
+		expected, err := ReadFully(in)
+		require.Nil(t, err)
+		require.True(t, equal(expected, next()), in)
+	}
+	// a symbol might continue, so it is returned once followed by more input
+	_, err := pw.Write([]byte("sym"))
+	require.Nil(t, err)
+	_, err = pw.Write([]byte("bol "))
+	require.Nil(t, err)
+	require.Equal(t, Symbol("symbol"), next())
+	require.Nil(t, pw.Close())
+	_, ok := <-forms
+	require.False(t, ok)
+}
@@ -1,6 +1,6 @@
 module github.com/EugenDueck/minsexp
 
-go 1.12
+go 1.13
 
 require (
 	github.com/pkg/errors v0.8.1
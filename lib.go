@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/shopspring/decimal"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -36,6 +38,12 @@ var (
 		"/":       divideFn,
 		"get":     getFn,
 		"set":     setFn,
+
+		// regular expressions
+		"re-find":    reFindFn,
+		"re-matches": reMatchesFn,
+		"re-seq":     reSeqFn,
+		"re-replace": reReplaceFn,
 	}
 )
 
@@ -44,14 +52,14 @@ func ifForm(env map[string]interface{}, lexicalScope []map[string]interface{}, a
 		return nil, errors.New("(if condition then [else]) expects a 'condition', a 'then' sexp, and may have an 'else' sexp")
 	}
 
-	conditionResult, err := Eval(env, lexicalScope, args[0])
+	conditionResult, err := eval(env, lexicalScope, args[0])
 	if err != nil {
 		return nil, err
 	}
 	if trueish(conditionResult) {
-		return Eval(env, lexicalScope, args[1])
+		return eval(env, lexicalScope, args[1])
 	} else if len(args) == 3 {
-		return Eval(env, lexicalScope, args[2])
+		return eval(env, lexicalScope, args[2])
 	} else {
 		return nil, nil
 	}
@@ -64,7 +72,7 @@ func trueish(value interface{}) bool {
 func doForm(env map[string]interface{}, lexicalScope []map[string]interface{}, args []interface{}) (interface{}, error) {
 	var lastResult interface{} = nil
 	for _, arg := range args {
-		result, err := Eval(env, lexicalScope, arg)
+		result, err := eval(env, lexicalScope, arg)
 		if err != nil {
 			return nil, err
 		}
@@ -76,7 +84,7 @@ func doForm(env map[string]interface{}, lexicalScope []map[string]interface{}, a
 func andForm(env map[string]interface{}, lexicalScope []map[string]interface{}, args []interface{}) (interface{}, error) {
 	var lastTrueish interface{} = true
 	for _, arg := range args {
-		result, err := Eval(env, lexicalScope, arg)
+		result, err := eval(env, lexicalScope, arg)
 		if err != nil {
 			return nil, err
 		}
@@ -92,7 +100,7 @@ func andForm(env map[string]interface{}, lexicalScope []map[string]interface{},
 func orForm(env map[string]interface{}, lexicalScope []map[string]interface{}, args []interface{}) (interface{}, error) {
 	var lastFalseish interface{} = nil
 	for _, arg := range args {
-		result, err := Eval(env, lexicalScope, arg)
+		result, err := eval(env, lexicalScope, arg)
 		if err != nil {
 			return nil, err
 		}
@@ -122,16 +130,7 @@ func equalsFn(args []interface{}) (interface{}, error) {
 	}
 	cmp := args[0]
 	for _, v := range args[1:] {
-		if d, ok := v.(decimal.Decimal); ok {
-			dCmp, bothDecimals := cmp.(decimal.Decimal)
-			if bothDecimals {
-				if dCmp.Cmp(d) != 0 {
-					return false, nil
-				}
-			} else {
-				return false, nil
-			}
-		} else if cmp != v {
+		if !equal(cmp, v) {
 			return false, nil
 		}
 	}
@@ -144,22 +143,138 @@ func notEqualsFn(args []interface{}) (interface{}, error) {
 	}
 	cmp := args[0]
 	for _, v := range args[1:] {
-		if d, ok := v.(decimal.Decimal); ok {
-			dCmp, bothDecimals := cmp.(decimal.Decimal)
-			if bothDecimals {
-				if dCmp.Cmp(d) != 0 {
-					return true, nil
-				}
-			} else {
-				return true, nil
-			}
-		} else if cmp != v {
+		if !equal(cmp, v) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// equal compares decimals and times by value, regexes by pattern, lists and vectors element-wise, and everything else
+// using ==. Values that contain themselves, like a list that is its own element, are compared without recursing forever
+func equal(a interface{}, b interface{}) bool {
+	return equalVisiting(a, b, nil)
+}
+
+// visiting are the pairs of lists, vectors and maps being compared. A pair that is compared again while it is being
+// compared is taken to be equal, as any difference is found by the comparison in progress
+func equalVisiting(a interface{}, b interface{}, visiting map[[2]visit]bool) bool {
+	switch a.(type) {
+	case []interface{}, Vector, Map:
+		va, okA := visitOf(a)
+		vb, okB := visitOf(b)
+		if okA && okB {
+			pair := [2]visit{va, vb}
+			if va == vb || visiting[pair] {
+				return true
+			}
+			if visiting == nil {
+				visiting = map[[2]visit]bool{}
+			}
+			visiting[pair] = true
+			defer delete(visiting, pair)
+		}
+	}
+	switch a := a.(type) {
+	case decimal.Decimal:
+		d, ok := b.(decimal.Decimal)
+		return ok && a.Cmp(d) == 0
+	case time.Time:
+		t, ok := b.(time.Time)
+		return ok && a.Equal(t)
+	case *regexp.Regexp:
+		re, ok := b.(*regexp.Regexp)
+		// compared as printed, as reading a printed regex may escape double quotes in its pattern, like a"b
+		return ok && printRegex(a) == printRegex(re)
+	case []interface{}:
+		l, ok := b.([]interface{})
+		return ok && equalElems(a, l, visiting)
+	case Vector:
+		v, ok := b.(Vector)
+		return ok && equalElems(a, v, visiting)
+	case Map:
+		m, ok := b.(Map)
+		if !ok || len(a) != len(m) {
+			return false
+		}
+		for _, entry := range a {
+			value, found := m.get(entry.Key, visiting)
+			if !found || !equalVisiting(entry.Value, value, visiting) {
+				return false
+			}
+		}
+		return true
+	}
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return a == nil && b == nil
+	}
+	return a == b
+}
+
+// mapKeys finds duplicate keys of a map being built in time proportional to its size, instead of comparing every key
+// with every other one. Keys are looked up by hash, with decimals, times and regexes replaced by a value that is the
+// same for keys equal considers equal. Lists, vectors and maps are compared using equal, but only with each other
+type mapKeys struct {
+	hashed      map[interface{}]bool
+	collections []interface{}
+}
+
+type decimalKey string
+
+type timeKey struct {
+	sec  int64
+	nsec int
+}
+
+type regexKey string
+
+func newMapKeys(size int) *mapKeys {
+	return &mapKeys{hashed: make(map[interface{}]bool, size)}
+}
+
+// add adds key, and returns false if an equal key has already been added
+func (keys *mapKeys) add(key interface{}) bool {
+	switch k := key.(type) {
+	case []interface{}, Vector, Map:
+		for _, other := range keys.collections {
+			if equal(other, key) {
+				return false
+			}
+		}
+		keys.collections = append(keys.collections, key)
+		return true
+	case decimal.Decimal:
+		// String drops trailing zeros, so that 1.0 and 1 are the same key
+		key = decimalKey(k.String())
+	case time.Time:
+		key = timeKey{k.Unix(), k.Nanosecond()}
+	case *regexp.Regexp:
+		key = regexKey(printRegex(k))
+	default:
+		// equal considers values that cannot be compared using == different from any other value
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return true
+		}
+	}
+	if keys.hashed[key] {
+		return false
+	}
+	keys.hashed[key] = true
+	return true
+}
+
+func equalElems(a []interface{}, b []interface{}, visiting map[[2]visit]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalVisiting(a[i], b[i], visiting) {
+			return false
+		}
+	}
+	return true
+}
+
 func compareFn(args []interface{}) (interface{}, error) {
 	if len(args) != 2 || reflect.TypeOf(args[0]) != reflect.TypeOf(args[1]) {
 		return nil, errors.New("compare expects two arguments of the same type")
@@ -276,9 +391,13 @@ func divideFn(args []interface{}) (interface{}, error) {
 
 func getFn(args []interface{}) (interface{}, error) {
 	if len(args) != 2 {
-		return nil, errors.New("Usage: (get <struct> <field-name>)")
+		return nil, errors.New("Usage: (get <struct> <field-name>) or (get <map> <key>)")
 	}
-	fieldName, ok := args[1].(string)
+	if m, ok := args[0].(Map); ok {
+		value, _ := m.Get(args[1])
+		return value, nil
+	}
+	fieldName, ok := fieldNameArg(args[1])
 	if !ok {
 		return nil, errors.New("Usage: (get <struct> <field-name>)")
 	}
@@ -299,7 +418,7 @@ func setFn(args []interface{}) (interface{}, error) {
 	}
 	obj := args[0]
 	for i := 1; i < len(args); i += 2 {
-		fieldName, ok := args[i].(string)
+		fieldName, ok := fieldNameArg(args[i])
 		if !ok {
 			return nil, errors.New("Usage: (set <struct> <<field-name> <value>>+)")
 		}
@@ -323,3 +442,15 @@ func setFn(args []interface{}) (interface{}, error) {
 	}
 	return obj, nil
 }
+
+// fieldNameArg accepts field names given as strings or keywords
+func fieldNameArg(arg interface{}) (string, bool) {
+	switch arg := arg.(type) {
+	case string:
+		return arg, true
+	case Keyword:
+		return string(arg), true
+	default:
+		return "", false
+	}
+}
@@ -2,7 +2,9 @@ package minsexp
 
 import (
 	"github.com/shopspring/decimal"
+	"regexp"
 	"testing"
+	"time"
 )
 import "github.com/stretchr/testify/require"
 
@@ -357,3 +359,78 @@ func TestSetFails(t *testing.T) {
 		require.Nil(t, evalledSexp, inputForm)
 	}
 }
+
+func TestEquals(t *testing.T) {
+	for inputForm, expectedOutput := range map[string]interface{}{
+		"(= [1 \"a\"] [1 \"a\"])":  true,
+		"(= [1 2] [1])":            false,
+		"(= [1] [[1]])":            false,
+		"(= [] [])":                true,
+		"(not= [1 [2]] [1 [2]])":   false,
+		"(not= [1 [2]] [1 [2 3]])": true,
+	} {
+		evalledSexp, err := ReadEval(nil, inputForm)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, expectedOutput, evalledSexp, inputForm)
+	}
+}
+
+func TestGetSetWithKeywords(t *testing.T) {
+	somePrice := decimal.NewFromFloat(98.765)
+	strukt := testStruct{somePrice, nil, nil, testA, nil, nil}
+	evalledSexp, err := ReadEval([]map[string]interface{}{{"obj": &strukt}}, "(get obj :Price)")
+	require.Nil(t, err)
+	require.Equal(t, somePrice, evalledSexp)
+
+	evalledSexp, err = ReadEval([]map[string]interface{}{{"obj": &strukt}}, `(get (set obj :TestType "b" "Price" 1) :TestType)`)
+	require.Nil(t, err)
+	require.Equal(t, testB, evalledSexp)
+	require.Zero(t, decimal.NewFromFloat(1).Cmp(strukt.Price))
+}
+
+func TestEqualCyclic(t *testing.T) {
+	l := []interface{}{Symbol("a"), nil}
+	l[1] = l
+	m := []interface{}{Symbol("a"), nil}
+	m[1] = m
+	n := []interface{}{Symbol("b"), nil}
+	n[1] = n
+	mapWithCycle := Map{{Keyword("k"), nil}}
+	mapWithCycle[0].Value = mapWithCycle
+
+	scope := []map[string]interface{}{{"l": l, "m": m, "n": n, "mc": mapWithCycle}}
+	for in, expected := range map[string]bool{
+		"(= l l)":                  true,
+		"(= l m)":                  true,
+		"(= l n)":                  false,
+		"(= l '(a (a b)))":         false,
+		"(= mc mc)":                true,
+		"(= mc {:k {:k 1}})":       false,
+		"(not= (get {l 1} m) nil)": true,
+	} {
+		result, err := ReadEval(scope, in)
+		require.Nil(t, err, in)
+		require.Equal(t, expected, result, in)
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	instant := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, dup := range [][2]interface{}{
+		{decimal.New(1, 0), decimal.New(10, -1)},
+		{instant, instant.In(time.FixedZone("CEST", 2*60*60))},
+		{regexp.MustCompile(`a"b`), regexp.MustCompile(`a\"b`)},
+		{Vector{decimal.New(1, 0)}, Vector{decimal.New(100, -2)}},
+		{Keyword("a"), Keyword("a")},
+		{nil, nil},
+	} {
+		keys := newMapKeys(2)
+		require.True(t, keys.add(dup[0]), Print(dup[0]))
+		require.False(t, keys.add(dup[1]), Print(dup[1]))
+	}
+
+	keys := newMapKeys(0)
+	for _, key := range []interface{}{"a", Symbol("a"), Keyword("a"), decimal.New(1, 0), decimal.New(11, -1), []interface{}{Symbol("a")}, Vector{Symbol("a")}, []int{1}, []int{1}} {
+		require.True(t, keys.add(key), Print(key))
+	}
+}
@@ -0,0 +1,140 @@
+package minsexp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseErrorKind tells what kind of syntax error a ParseError is
+type ParseErrorKind int
+
+const (
+	// UnexpectedChar is a character that is not allowed where it was found, e.g. a ',' or an invalid escape sequence
+	UnexpectedChar ParseErrorKind = iota
+	// UnterminatedString is a string that is not terminated by a double quote
+	UnterminatedString
+	// UnbalancedParen is a closing ')', ']' or '}' without a matching opening one
+	UnbalancedParen
+	// BadNumber is a malformed number, e.g. 1x or 0b2
+	BadNumber
+	// Incomplete is a list, vector, map or block comment that is cut off by the end of the input
+	Incomplete
+	// DuplicateKey is a map containing the same key twice
+	DuplicateKey
+	// ReaderMacroFailed is a form rejected by a reader macro, see Readtable
+	ReaderMacroFailed
+	// LimitExceeded is input exceeding one of the limits set in ReaderOptions, like MaxDepth
+	LimitExceeded
+	// BadRegex is a regex literal, like #"[a-", that is not a valid regular expression
+	BadRegex
+)
+
+func (k ParseErrorKind) String() string {
+	switch k {
+	case UnexpectedChar:
+		return "UnexpectedChar"
+	case UnterminatedString:
+		return "UnterminatedString"
+	case UnbalancedParen:
+		return "UnbalancedParen"
+	case BadNumber:
+		return "BadNumber"
+	case Incomplete:
+		return "Incomplete"
+	case DuplicateKey:
+		return "DuplicateKey"
+	case ReaderMacroFailed:
+		return "ReaderMacroFailed"
+	case LimitExceeded:
+		return "LimitExceeded"
+	case BadRegex:
+		return "BadRegex"
+	default:
+		return fmt.Sprintf("ParseErrorKind(%d)", int(k))
+	}
+}
+
+// ParseError is returned by the reader for texts that are not valid sexps.
+// Offset is the 0-based byte offset of the offending character and RuneOffset its 0-based offset in runes.
+// Line and Column are 1-based, with Column counted in runes
+type ParseError struct {
+	Kind       ParseErrorKind
+	Msg        string
+	File       string
+	Offset     int
+	Line       int
+	Column     int
+	RuneOffset int
+	// Expected describes what the reader expected at the end of the input or at the character described by Found,
+	// e.g. "')'" or "digit". It is empty if there is no single thing that was expected
+	Expected string
+	// Found is the character the reader did not expect, e.g. "']'", or "end of input"
+	Found string
+	// Err is the error returned by a reader macro, if the error is a ReaderMacroFailed, or by regexp.Compile,
+	// if the error is a BadRegex
+	Err error
+
+	idx     int    // the index of the offending character in the text being read
+	atEnd   bool   // whether the error was caused by the end of the input
+	snippet string // the source line and a caret pointing at the offending character, if requested
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	if e.File != "" {
+		sb.WriteString(e.File)
+		sb.WriteByte(':')
+	}
+	if e.Line > 0 {
+		sb.WriteString(e.Position().String())
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Msg)
+	if e.snippet != "" {
+		sb.WriteByte('\n')
+		sb.WriteString(e.snippet)
+	}
+	return sb.String()
+}
+
+// Unwrap returns Err
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns where the error occurred
+func (e *ParseError) Position() Position {
+	return Position{e.Offset, e.Line, e.Column, e.RuneOffset}
+}
+
+// IsIncomplete tells whether the text could become a valid sexp if more text were appended,
+// i.e. whether the error was caused by the end of the input rather than by an invalid character
+func (e *ParseError) IsIncomplete() bool {
+	return e.atEnd
+}
+
+func parseError(kind ParseErrorKind, idx int, msg string) *ParseError {
+	return &ParseError{Kind: kind, Msg: msg, Offset: idx, idx: idx}
+}
+
+// expecting sets Expected, and Found to a description of the character at foundIdx in s
+func (e *ParseError) expecting(expected string, s string, foundIdx int) *ParseError {
+	e.Expected = expected
+	e.Found = describeChar(s, foundIdx)
+	e.atEnd = foundIdx >= len(s)
+	return e
+}
+
+func describeChar(s string, idx int) string {
+	if idx >= len(s) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRuneInString(s[idx:])
+	return fmt.Sprintf("%q", r)
+}
+
+func isIncomplete(err error) bool {
+	parseErr, ok := err.(*ParseError)
+	return ok && parseErr.atEnd
+}
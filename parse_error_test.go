@@ -0,0 +1,61 @@
+package minsexp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	for in, expected := range map[string]ParseError{
+		"(a\n  b":       {Kind: Incomplete, Offset: 6, Line: 2, Column: 4, RuneOffset: 6, Expected: "')'", Found: "end of input"},
+		"[1 2)":         {Kind: UnbalancedParen, Offset: 4, Line: 1, Column: 5, RuneOffset: 4, Expected: "']'", Found: "')'"},
+		")":             {Kind: UnbalancedParen, Offset: 0, Line: 1, Column: 1, RuneOffset: 0, Expected: "sexp", Found: "')'"},
+		"(a \"bc":       {Kind: UnterminatedString, Offset: 6, Line: 1, Column: 7, RuneOffset: 6, Expected: "'\"'", Found: "end of input"},
+		"\"a\\":         {Kind: UnterminatedString, Offset: 2, Line: 1, Column: 3, RuneOffset: 2, Expected: "escape sequence", Found: "end of input"},
+		"\"a\\x\"":      {Kind: UnexpectedChar, Offset: 2, Line: 1, Column: 3, RuneOffset: 2, Expected: "escape sequence", Found: "'x'"},
+		"(+ 1\n 2x)":    {Kind: BadNumber, Offset: 7, Line: 2, Column: 3, RuneOffset: 7, Expected: "digit", Found: "'x'"},
+		"1e":            {Kind: BadNumber, Offset: 1, Line: 1, Column: 2, RuneOffset: 1, Expected: "digit", Found: "end of input"},
+		"(a , b)":       {Kind: UnexpectedChar, Offset: 3, Line: 1, Column: 4, RuneOffset: 3, Expected: "sexp", Found: "','"},
+		"{:a 1 :b}":     {Kind: UnexpectedChar, Offset: 8, Line: 1, Column: 9, RuneOffset: 8, Expected: "value", Found: "'}'"},
+		"{:a 1 :a 2}":   {Kind: DuplicateKey, Offset: 6, Line: 1, Column: 7, RuneOffset: 6},
+		"(a #| b":       {Kind: Incomplete, Offset: 7, Line: 1, Column: 8, RuneOffset: 7, Expected: "'|#'", Found: "end of input"},
+		"(a :)":         {Kind: UnexpectedChar, Offset: 3, Line: 1, Column: 4, RuneOffset: 3, Expected: "keyword name", Found: "')'"},
+		"(a\n\n  (b c]": {Kind: UnbalancedParen, Offset: 10, Line: 3, Column: 7, RuneOffset: 10, Expected: "')'", Found: "']'"},
+	} {
+		_, _, err := Read(in, 0)
+		require.NotNil(t, err, in)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), in)
+		require.Equal(t, expected.Kind, parseErr.Kind, in)
+		require.Equal(t, expected.Position(), parseErr.Position(), in)
+		require.Equal(t, expected.Expected, parseErr.Expected, in)
+		require.Equal(t, expected.Found, parseErr.Found, in)
+		require.Equal(t, expected.Found == "end of input", parseErr.IsIncomplete(), in)
+		require.True(t, strings.HasPrefix(err.Error(), parseErr.Position().String()+": "), in)
+	}
+}
+
+func TestParseErrorWithOptions(t *testing.T) {
+	_, _, err := ReadWithOptions("(+ 1\n  2", 0, ReaderOptions{File: "rules.sexp"})
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, Incomplete, parseErr.Kind)
+	require.True(t, parseErr.IsIncomplete())
+	require.Equal(t, "rules.sexp", parseErr.File)
+	require.Equal(t, Position{8, 2, 4, 8}, parseErr.Position())
+	require.Equal(t, "rules.sexp:2:4: reached end of input parsing list starting at 1:1\n  2\n   ^", err.Error())
+
+	_, err = ReadFully("(a) b")
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, UnexpectedChar, parseErr.Kind)
+	require.Equal(t, Position{4, 1, 5, 4}, parseErr.Position())
+	require.Equal(t, "end of input", parseErr.Expected)
+	require.Equal(t, "'b'", parseErr.Found)
+}
+
+func TestParseErrorKindString(t *testing.T) {
+	require.Equal(t, "UnbalancedParen", UnbalancedParen.String())
+	require.Equal(t, "ParseErrorKind(42)", ParseErrorKind(42).String())
+}
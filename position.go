@@ -0,0 +1,270 @@
+package minsexp
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a location in a source text. Offset is the 0-based byte offset, RuneOffset the 0-based offset in runes.
+// Line and Column are 1-based, with Column counted in runes
+type Position struct {
+	Offset     int
+	Line       int
+	Column     int
+	RuneOffset int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the part of a source text a form was read from. End is the position right after the form
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// SourceMap records the spans of forms read by ReadWithOptions.
+// As there is no way to tell apart two equal symbols or numbers, forms are identified by where they are stored:
+// the span of a list, vector or map can be looked up using the form itself, the span of any form using the
+// list, vector or map containing it
+type SourceMap struct {
+	srcs  []*source
+	lists map[*interface{}]Span // keyed by the address of the first element of a list or vector, or the first key of a map
+	elems map[*interface{}]Span // keyed by the address of the list element, map key or map value holding the form
+}
+
+// Span returns the span of a non-empty list, vector or map
+func (sm *SourceMap) Span(form interface{}) (Span, bool) {
+	var first *interface{}
+	switch form := form.(type) {
+	case []interface{}:
+		if len(form) > 0 {
+			first = &form[0]
+		}
+	case Vector:
+		if len(form) > 0 {
+			first = &form[0]
+		}
+	case Map:
+		if len(form) > 0 {
+			first = &form[0].Key
+		}
+	}
+	if first == nil || sm.lists == nil {
+		return Span{}, false
+	}
+	span, ok := sm.lists[first]
+	return span, ok
+}
+
+// ElemSpan returns the span of the i-th element of list
+func (sm *SourceMap) ElemSpan(list []interface{}, i int) (Span, bool) {
+	if i < 0 || i >= len(list) || sm.elems == nil {
+		return Span{}, false
+	}
+	span, ok := sm.elems[&list[i]]
+	return span, ok
+}
+
+// EntrySpans returns the spans of the key and the value of the i-th entry of m
+func (sm *SourceMap) EntrySpans(m Map, i int) (keySpan Span, valueSpan Span, ok bool) {
+	if i < 0 || i >= len(m) || sm.elems == nil {
+		return Span{}, Span{}, false
+	}
+	keySpan, ok = sm.elems[&m[i].Key]
+	if !ok {
+		return Span{}, Span{}, false
+	}
+	valueSpan, ok = sm.elems[&m[i].Value]
+	return keySpan, valueSpan, ok
+}
+
+// Eval is like Eval, but prefixes errors with the "file:line:col" of the form that caused them,
+// and appends the source line with a caret pointing at the form.
+// Errors that cannot be located are returned unchanged
+func (sm *SourceMap) Eval(env map[string]interface{}, lexicalScope []map[string]interface{}, sexp interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = errors.WithStack(panicError(r))
+			if p, ok := r.(*evalPanic); ok {
+				err = sm.annotate(&evalError{err: err, slot: p.slot, isList: true})
+			}
+		}
+	}()
+	result, err = eval(env, lexicalScope, sexp)
+	if evalErr, ok := err.(*evalError); ok {
+		return nil, sm.annotate(evalErr)
+	}
+	return result, err
+}
+
+// annotate returns evalErr with the location of the form that caused it, or the error it wraps if it cannot be located
+func (sm *SourceMap) annotate(evalErr *evalError) error {
+	var span Span
+	var ok bool
+	if evalErr.isList {
+		span, ok = sm.lists[evalErr.slot]
+	} else {
+		span, ok = sm.elems[evalErr.slot]
+	}
+	if !ok {
+		return evalErr.err
+	}
+	for i := len(sm.srcs) - 1; i >= 0; i-- {
+		src := sm.srcs[i]
+		idx := span.Start.Offset - src.base.Offset
+		if idx >= 0 && idx <= len(src.text) {
+			annotated := *evalErr
+			annotated.msg = src.annotate(idx, evalErr.err.Error())
+			return &annotated
+		}
+	}
+	return evalErr.err
+}
+
+// source returns the source to record spans of forms read from text in
+func (sm *SourceMap) source(file string, text string, base Position) *source {
+	if len(sm.srcs) > 0 {
+		last := sm.srcs[len(sm.srcs)-1]
+		if last.text == text && last.file == file && last.base == base {
+			return last
+		}
+	}
+	src := newSource(file, text, base)
+	sm.srcs = append(sm.srcs, src)
+	return src
+}
+
+func (sm *SourceMap) record(list []interface{}, listSpan Span, elemSpans []Span) {
+	if len(list) == 0 {
+		return
+	}
+	sm.init()
+	sm.lists[&list[0]] = listSpan
+	for i := range list {
+		sm.elems[&list[i]] = elemSpans[i]
+	}
+}
+
+func (sm *SourceMap) recordMap(m Map, mapSpan Span, elemSpans []Span) {
+	if len(m) == 0 {
+		return
+	}
+	sm.init()
+	sm.lists[&m[0].Key] = mapSpan
+	for i := range m {
+		sm.elems[&m[i].Key] = elemSpans[2*i]
+		sm.elems[&m[i].Value] = elemSpans[2*i+1]
+	}
+}
+
+func (sm *SourceMap) init() {
+	if sm.lists == nil {
+		sm.lists = make(map[*interface{}]Span)
+		sm.elems = make(map[*interface{}]Span)
+	}
+}
+
+// source is a text that forms are read from. The text starts at base, which may be in the middle of a line.
+// The lines are only looked up as far as positions are asked for, so that reading a form from a long text
+// takes time in proportion to the form
+type source struct {
+	file                string
+	text                string
+	base                Position
+	lineStartIdxs       []int
+	lineStartRuneCounts []int // the number of runes before each line
+	scannedIdx          int   // the index up to which the lines have been looked up
+	scannedRuneCount    int   // the number of runes before scannedIdx
+	sb                  strings.Builder
+}
+
+var startPosition = Position{0, 1, 1, 0}
+
+func newSource(file string, text string, base Position) *source {
+	return &source{file: file, text: text, base: base, lineStartIdxs: []int{0}, lineStartRuneCounts: []int{0}}
+}
+
+// extend appends text to a source created with an empty text, like the one of a Decoder growing as forms are read
+func (src *source) extend(text string) {
+	src.sb.WriteString(text)
+	src.text = src.sb.String()
+}
+
+// scanTo looks up the lines starting up to idx
+func (src *source) scanTo(idx int) {
+	if idx <= src.scannedIdx {
+		return
+	}
+	for i, c := range src.text[src.scannedIdx:idx] {
+		src.scannedRuneCount++
+		if c == '\n' {
+			src.lineStartIdxs = append(src.lineStartIdxs, src.scannedIdx+i+1)
+			src.lineStartRuneCounts = append(src.lineStartRuneCounts, src.scannedRuneCount)
+		}
+	}
+	src.scannedIdx = idx
+}
+
+func (src *source) position(idx int) Position {
+	src.scanTo(idx)
+	line := sort.Search(len(src.lineStartIdxs), func(i int) bool { return src.lineStartIdxs[i] > idx }) - 1
+	column := utf8.RuneCountInString(src.text[src.lineStartIdxs[line]:idx])
+	runeOffset := src.base.RuneOffset + src.lineStartRuneCounts[line] + column
+	if line == 0 {
+		column += src.base.Column - 1
+	}
+	return Position{src.base.Offset + idx, src.base.Line + line, column + 1, runeOffset}
+}
+
+func (src *source) span(startIdx int, endIdx int) Span {
+	return Span{src.position(startIdx), src.position(endIdx)}
+}
+
+// annotate formats msg as "file:line:col: msg", followed by the source line containing idx and a caret pointing at idx
+func (src *source) annotate(idx int, msg string) string {
+	pos := src.position(idx)
+	var sb strings.Builder
+	if src.file != "" {
+		sb.WriteString(src.file)
+		sb.WriteByte(':')
+	}
+	sb.WriteString(pos.String())
+	sb.WriteString(": ")
+	sb.WriteString(msg)
+	sb.WriteByte('\n')
+	sb.WriteString(src.snippet(idx))
+	return sb.String()
+}
+
+// snippet returns the source line containing idx, followed by a line with a caret pointing at idx
+func (src *source) snippet(idx int) string {
+	var sb strings.Builder
+	line := src.position(idx).Line - src.base.Line
+	lineStartIdx := src.lineStartIdxs[line]
+	lineEndIdx := len(src.text)
+	if i := strings.IndexByte(src.text[lineStartIdx:], '\n'); i >= 0 {
+		lineEndIdx = lineStartIdx + i
+	}
+	lineText := strings.TrimRight(src.text[lineStartIdx:lineEndIdx], "\r")
+	sb.WriteString(lineText)
+	sb.WriteByte('\n')
+	caretIdx := idx
+	if caretIdx > lineStartIdx+len(lineText) {
+		caretIdx = lineStartIdx + len(lineText)
+	}
+	for _, c := range src.text[lineStartIdx:caretIdx] {
+		if c == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
@@ -0,0 +1,151 @@
+package minsexp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSourceMapSpans(t *testing.T) {
+	src := "(+ 1\n  (* a \"b\"))"
+	sm := &SourceMap{}
+	sexp, idx, err := ReadWithOptions(src, 0, ReaderOptions{File: "rules.sexp", SourceMap: sm})
+	require.Nil(t, err)
+	require.Equal(t, len(src), idx)
+
+	list := sexp.([]interface{})
+	span, ok := sm.Span(list)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{0, 1, 1, 0}, Position{len(src), 2, 13, len(src)}}, span)
+
+	span, ok = sm.ElemSpan(list, 1)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{3, 1, 4, 3}, Position{4, 1, 5, 4}}, span)
+
+	inner := list[2].([]interface{})
+	span, ok = sm.ElemSpan(list, 2)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{7, 2, 3, 7}, Position{16, 2, 12, 16}}, span)
+	innerSpan, ok := sm.Span(inner)
+	require.True(t, ok)
+	require.Equal(t, span, innerSpan)
+
+	span, ok = sm.ElemSpan(inner, 2)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{12, 2, 8, 12}, Position{15, 2, 11, 15}}, span)
+
+	_, ok = sm.ElemSpan(inner, 3)
+	require.False(t, ok)
+	_, ok = sm.Span([]interface{}{Symbol("+")})
+	require.False(t, ok)
+}
+
+func TestReadWithOptionsErrors(t *testing.T) {
+	for in, expectedErr := range map[string]string{
+		"(+ 1\n  (* a 1x))": "rules.sexp:2:9: not a valid number, unexpected character 'x': 1x\n  (* a 1x))\n        ^",
+		"(+ 1\n\t(.":        "rules.sexp:2:3: Syntax error. Unexpected character '.'\n\t(.\n\t ^",
+		"(+ 1":              "rules.sexp:1:5: reached end of input parsing list starting at 1:1\n(+ 1\n    ^",
+		"(größe\t製品 1x)":    "rules.sexp:1:12: not a valid number, unexpected character 'x': 1x\n(größe\t製品 1x)\n      \t    ^",
+	} {
+		_, _, err := ReadWithOptions(in, 0, ReaderOptions{File: "rules.sexp"})
+		require.NotNil(t, err, in)
+		require.Equal(t, expectedErr, err.Error(), in)
+	}
+}
+
+func TestSourceMapAnnotate(t *testing.T) {
+	for in, expectedErr := range map[string]string{
+		"(+ 1\n   (* a 2))":            "rules.sexp:2:7: Unbound name a\n   (* a 2))\n      ^",
+		"(+ 1\n   (* \"a\" 2))":        "rules.sexp:2:4: * works on numbers of type decimal.Decimal\n   (* \"a\" 2))\n   ^",
+		"(let a 1\n  (if (+ a)))":      "rules.sexp:2:3: (if condition then [else]) expects a 'condition', a 'then' sexp, and may have an 'else' sexp\n  (if (+ a)))\n  ^",
+		"(let a 1\n     2 3\n  a)":     "rules.sexp:2:6: let needs an uneven number of arguments: name-symbol/sexp pairs and one sexp\n     2 3\n     ^",
+		"(do 1\n  (if (undefined) 1))": "rules.sexp:2:8: Unbound name undefined\n  (if (undefined) 1))\n       ^",
+	} {
+		sm := &SourceMap{}
+		sexp, _, err := ReadWithOptions(in, 0, ReaderOptions{File: "rules.sexp", SourceMap: sm})
+		require.Nil(t, err, in)
+
+		_, err = Eval(StdEnv, nil, sexp)
+		require.NotNil(t, err, in)
+		require.NotEqual(t, expectedErr, err.Error(), in)
+
+		_, err = sm.Eval(StdEnv, nil, sexp)
+		require.NotNil(t, err, in)
+		require.Equal(t, expectedErr, err.Error(), in)
+	}
+}
+
+type hostError struct{ code int }
+
+func (e hostError) Error() string { return fmt.Sprintf("host error %d", e.code) }
+
+func TestEvalErrorIdentity(t *testing.T) {
+	errSentinel := errors.New("sentinel")
+	env := map[string]interface{}{
+		"do":   StdEnv["do"],
+		"if":   StdEnv["if"],
+		"true": true,
+		"sentinel": func(args []interface{}) (interface{}, error) {
+			return nil, errSentinel
+		},
+		"host": func(args []interface{}) (interface{}, error) {
+			return nil, hostError{42}
+		},
+		"boom": func(args []interface{}) (interface{}, error) {
+			panic("boom")
+		},
+	}
+	src := "(do 1\n  (if true (sentinel)))\n(do\n  [(host)])\n(do (boom))"
+	sm := &SourceMap{}
+	forms, err := ReadAllWithOptions(src, ReaderOptions{File: "rules.sexp", SourceMap: sm})
+	require.Nil(t, err)
+
+	// Eval returns errors as they were returned
+	_, err = Eval(env, nil, forms[0])
+	require.True(t, err == errSentinel)
+	_, err = Eval(env, nil, forms[1])
+	require.Equal(t, hostError{42}, err)
+
+	// SourceMap.Eval locates them
+	_, err = sm.Eval(env, nil, forms[0])
+	require.Equal(t, "rules.sexp:2:12: sentinel\n  (if true (sentinel)))\n           ^", err.Error())
+	require.True(t, errors.Is(err, errSentinel))
+	_, err = sm.Eval(env, nil, forms[1])
+	require.Equal(t, "rules.sexp:4:4: host error 42\n  [(host)])\n   ^", err.Error())
+	var hostErr hostError
+	require.True(t, errors.As(err, &hostErr))
+	require.Equal(t, 42, hostErr.code)
+
+	// and the panics
+	_, err = Eval(env, nil, forms[2])
+	require.Equal(t, "minsexp: boom", err.Error())
+	_, err = sm.Eval(env, nil, forms[2])
+	require.Equal(t, "rules.sexp:5:5: minsexp: boom\n(do (boom))\n    ^", err.Error())
+}
+
+func TestSourceMapMapsAndVectors(t *testing.T) {
+	src := "{a [1 x]\n b 2}"
+	sm := &SourceMap{}
+	sexp, _, err := ReadWithOptions(src, 0, ReaderOptions{File: "rules.sexp", SourceMap: sm})
+	require.Nil(t, err)
+
+	m := sexp.(Map)
+	span, ok := sm.Span(m)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{0, 1, 1, 0}, Position{len(src), 2, 6, len(src)}}, span)
+
+	keySpan, valueSpan, ok := sm.EntrySpans(m, 1)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{10, 2, 2, 10}, Position{11, 2, 3, 11}}, keySpan)
+	require.Equal(t, Span{Position{12, 2, 4, 12}, Position{13, 2, 5, 13}}, valueSpan)
+
+	v := m[0].Value.(Vector)
+	span, ok = sm.Span(v)
+	require.True(t, ok)
+	require.Equal(t, Span{Position{3, 1, 4, 3}, Position{8, 1, 9, 8}}, span)
+
+	_, err = sm.Eval(StdEnv, []map[string]interface{}{{"a": "a", "b": "b"}}, sexp)
+	require.NotNil(t, err)
+	require.Equal(t, "rules.sexp:1:7: Unbound name x\n{a [1 x]\n      ^", err.Error())
+}
@@ -0,0 +1,279 @@
+package minsexp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultWidth is the line width PrettyPrint uses if PrettyOptions.Width is 0
+const DefaultWidth = 80
+
+// PrettyOptions control the layout of PrettyPrint
+type PrettyOptions struct {
+	// Width is the maximum line width in runes. Forms that are too wide are broken into several lines,
+	// atoms that are too wide are not
+	Width int
+	// IndentStyles are the styles of lists by the symbol at their head. They take precedence over StdIndentStyles
+	IndentStyles map[Symbol]IndentStyle
+}
+
+// IndentStyle tells PrettyPrint how to lay out a list that does not fit on a line, by the symbol at its head.
+// Lists without a style are laid out like function calls: the first argument follows the head,
+// and the other arguments are aligned with it:
+//
+//	(+ price
+//	   shipping)
+type IndentStyle struct {
+	// Distinguished is the number of arguments following the head on its line, like the condition of if
+	Distinguished int
+	// Body indents the arguments following the distinguished ones by two spaces, like the branches of if,
+	// instead of aligning them with the first argument
+	Body bool
+	// Pairs puts the arguments following the distinguished ones on a line per pair, aligned with the first pair,
+	// like the bindings of let. With Body, the last argument is the body
+	Pairs bool
+}
+
+var (
+	// StdIndentStyles are the indent styles of the built-in special forms. Styles of custom special forms can be
+	// added here, or passed in PrettyOptions.IndentStyles
+	StdIndentStyles = map[Symbol]IndentStyle{
+		"let": {Body: true, Pairs: true},
+		"if":  {Distinguished: 1, Body: true},
+		"do":  {Body: true},
+	}
+)
+
+// PrettyPrint prints x like Print does, but breaks forms that do not fit in opts.Width into several lines,
+// indented according to their IndentStyle:
+//
+//	(let price (get order :price)
+//	     qty (get order :qty)
+//	  (if (> qty 10)
+//	    (* price qty 0.9)
+//	    (* price qty)))
+func PrettyPrint(x interface{}, opts PrettyOptions) string {
+	if opts.Width == 0 {
+		opts.Width = DefaultWidth
+	}
+	p := prettyPrinter{opts: opts, visiting: map[visit]bool{}}
+	var sb strings.Builder
+	p.layout(&sb, p.build(x), 0)
+	return sb.String()
+}
+
+type prettyPrinter struct {
+	opts PrettyOptions
+	// visiting are the lists, vectors and maps being built, to print cycles as #<cycle>
+	visiting map[visit]bool
+}
+
+// prettyNode is a form to be laid out by PrettyPrint, along with its width if printed on one line,
+// so that it is printed only once, whatever its depth
+type prettyNode struct {
+	// text is the printed form of an atom, the prefix of a prefixed form, like ', or the opening delimiter of a
+	// list, vector or map
+	text     string
+	close    string
+	children []*prettyNode
+	width    int
+	// pairs is set for maps, whose children are keys and values
+	pairs bool
+	// head is the symbol at the head of a list, if any
+	head    Symbol
+	hasHead bool
+}
+
+// build returns the node to lay out x with
+func (p prettyPrinter) build(x interface{}) *prettyNode {
+	if v, ok := visitOf(x); ok {
+		if p.visiting[v] {
+			return &prettyNode{text: cycleMarker, width: len(cycleMarker)}
+		}
+		p.visiting[v] = true
+		defer delete(p.visiting, v)
+	}
+	var n *prettyNode
+	switch x := x.(type) {
+	case []interface{}:
+		if len(x) == 2 {
+			if sym, ok := x[0].(Symbol); ok {
+				// printed like Print does, see printSexp
+				if prefix, ok := readerMacroPrefixes[sym]; ok && (prefix != "~" || !isSymbolStartingWith(x[1], "@")) {
+					child := p.build(x[1])
+					return &prettyNode{text: prefix, children: []*prettyNode{child}, width: len(prefix) + child.width}
+				}
+			}
+		}
+		n = &prettyNode{text: "(", close: ")", children: p.buildAll(x)}
+		if len(x) > 0 {
+			n.head, n.hasHead = x[0].(Symbol)
+		}
+	case Vector:
+		n = &prettyNode{text: "[", close: "]", children: p.buildAll(x)}
+	case Map:
+		elems := make([]interface{}, 0, 2*len(x))
+		for _, entry := range x {
+			elems = append(elems, entry.Key, entry.Value)
+		}
+		n = &prettyNode{text: "{", close: "}", children: p.buildAll(elems), pairs: true}
+	default:
+		text := Print(x)
+		return &prettyNode{text: text, width: utf8.RuneCountInString(text)}
+	}
+	n.width = 2
+	for i, child := range n.children {
+		if i > 0 {
+			n.width++
+		}
+		n.width += child.width
+	}
+	return n
+}
+
+func (p prettyPrinter) buildAll(elems []interface{}) []*prettyNode {
+	nodes := make([]*prettyNode, len(elems))
+	for i, elem := range elems {
+		nodes[i] = p.build(elem)
+	}
+	return nodes
+}
+
+// layout writes n to sb, starting at column col, and returns the column after it
+func (p prettyPrinter) layout(sb *strings.Builder, n *prettyNode, col int) int {
+	if col+n.width <= p.opts.Width {
+		writeFlat(sb, n)
+		return col + n.width
+	}
+	if n.close == "" {
+		// an atom, or a prefixed form
+		sb.WriteString(n.text)
+		if len(n.children) == 0 {
+			return col + n.width
+		}
+		return p.layout(sb, n.children[0], col+len(n.text))
+	}
+	if len(n.children) == 0 {
+		writeFlat(sb, n)
+		return col + n.width
+	}
+	if n.hasHead {
+		return p.layoutCall(sb, n, col)
+	}
+	sb.WriteString(n.text)
+	if n.pairs {
+		col = p.layoutPairs(sb, n.children, col+1)
+	} else {
+		col = p.layoutAligned(sb, n.children, col+1)
+	}
+	sb.WriteString(n.close)
+	return col + 1
+}
+
+// writeFlat writes n on one line
+func writeFlat(sb *strings.Builder, n *prettyNode) {
+	sb.WriteString(n.text)
+	for i, child := range n.children {
+		if i > 0 && n.close != "" {
+			sb.WriteByte(' ')
+		}
+		writeFlat(sb, child)
+	}
+	sb.WriteString(n.close)
+}
+
+// layoutCall writes a list with a symbol at its head, laid out according to the IndentStyle of the symbol
+func (p prettyPrinter) layoutCall(sb *strings.Builder, n *prettyNode, col int) int {
+	style, ok := p.opts.IndentStyles[n.head]
+	if !ok {
+		style = StdIndentStyles[n.head]
+	}
+	head, args := n.children[0], n.children[1:]
+	sb.WriteString("(")
+	writeFlat(sb, head)
+	argCol := col + 1 + head.width + 1
+	if len(args) == 0 {
+		sb.WriteString(")")
+		return argCol
+	}
+
+	// the head line
+	distinguished := style.Distinguished
+	if !style.Body && !style.Pairs && distinguished == 0 {
+		distinguished = 1
+	}
+	if distinguished > len(args) {
+		distinguished = len(args)
+	}
+	c := argCol - 1
+	for _, arg := range args[:distinguished] {
+		sb.WriteString(" ")
+		c = p.layout(sb, arg, c+1)
+	}
+	rest := args[distinguished:]
+
+	var body []*prettyNode
+	if style.Body {
+		body = rest
+		if style.Pairs {
+			body = rest[len(rest)-len(rest)%2:]
+			rest = rest[:len(rest)-len(rest)%2]
+		} else {
+			rest = nil
+		}
+	}
+	if len(rest) > 0 {
+		if distinguished == 0 {
+			sb.WriteString(" ")
+		} else {
+			newline(sb, argCol)
+		}
+		if style.Pairs {
+			c = p.layoutPairs(sb, rest, argCol)
+		} else {
+			c = p.layoutAligned(sb, rest, argCol)
+		}
+	}
+	for _, arg := range body {
+		newline(sb, col+2)
+		c = p.layout(sb, arg, col+2)
+	}
+	sb.WriteString(")")
+	return c + 1
+}
+
+// layoutAligned writes nodes on a line each, starting at column col
+func (p prettyPrinter) layoutAligned(sb *strings.Builder, nodes []*prettyNode, col int) int {
+	c := col
+	for i, n := range nodes {
+		if i > 0 {
+			newline(sb, col)
+		}
+		c = p.layout(sb, n, col)
+	}
+	return c
+}
+
+// layoutPairs writes nodes on a line per pair, like the entries of a map, starting at column col
+func (p prettyPrinter) layoutPairs(sb *strings.Builder, nodes []*prettyNode, col int) int {
+	c := col
+	for i := 0; i < len(nodes); i += 2 {
+		if i > 0 {
+			newline(sb, col)
+		}
+		c = p.layout(sb, nodes[i], col)
+		if i+1 < len(nodes) {
+			sb.WriteString(" ")
+			c = p.layout(sb, nodes[i+1], c+1)
+		}
+	}
+	return c
+}
+
+// newline starts a new line, indented to column col
+func newline(sb *strings.Builder, col int) {
+	sb.WriteByte('\n')
+	for i := 0; i < col; i++ {
+		sb.WriteByte(' ')
+	}
+}
@@ -0,0 +1,82 @@
+package minsexp
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestPrettyPrint(t *testing.T) {
+	for in, expected := range map[string]string{
+		"(let price (get order :price) qty (get order :qty) (if (> qty 10) (* price qty 0.9) (* price qty)))": `
+(let price (get order :price)
+     qty (get order :qty)
+  (if (> qty 10)
+    (* price qty 0.9)
+    (* price qty)))`,
+		"(do (set order :discount 0.1) (set order :total (* (get order :price) (get order :qty))) 'ok)": `
+(do
+  (set order :discount 0.1)
+  (set order
+       :total
+       (* (get order :price)
+          (get order :qty)))
+  'ok)`,
+		`[{:sku "A-1" :price 12.5 :tags ["new" "sale"]} {:sku "B-2" :price 3 :tags []} ((f x) y z)]`: `
+[{:sku "A-1"
+  :price 12.5
+  :tags ["new" "sale"]}
+ {:sku "B-2" :price 3 :tags []}
+ ((f x) y z)]`,
+		`(and (re-matches #"[A-Z]{3}-\d+" sku) (not= country "DE") (> (get order :total) 100))`: `
+(and (re-matches #"[A-Z]{3}-\d+" sku)
+     (not= country "DE")
+     (> (get order :total) 100))`,
+		"'(a-long-symbol another-long-symbol yet-another-one)": `
+'(a-long-symbol another-long-symbol
+                yet-another-one)`,
+		"(if (a) (b))": "\n(if (a) (b))",
+		"(größe-des-produkts größe-der-verpackung x)": "\n(größe-des-produkts größe-der-verpackung\n                    x)",
+	} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		printed := PrettyPrint(sexp, PrettyOptions{Width: 40})
+		require.Equal(t, expected[1:], printed, in)
+		for _, line := range strings.Split(printed, "\n") {
+			require.True(t, len([]rune(line)) <= 40, line)
+		}
+
+		reread, err := ReadFully(printed)
+		require.Nil(t, err, in)
+		require.True(t, equal(sexp, reread), in)
+	}
+
+	sexp, _ := ReadFully("(when (> qty 10) (set order :discount 0.1) (set order :note \"bulk\"))")
+	expected := "(when\n  (> qty 10)\n  (set order :discount 0.1)\n  (set order :note \"bulk\"))"
+	require.Equal(t, expected, PrettyPrint(sexp, PrettyOptions{Width: 40, IndentStyles: map[Symbol]IndentStyle{"when": {Body: true}}}))
+
+	StdIndentStyles["when"] = IndentStyle{Distinguished: 1, Body: true}
+	defer delete(StdIndentStyles, "when")
+	expected = "(when (> qty 10)\n  (set order :discount 0.1)\n  (set order :note \"bulk\"))"
+	require.Equal(t, expected, PrettyPrint(sexp, PrettyOptions{Width: 40}))
+
+	// the default width
+	require.Equal(t, Print(sexp), PrettyPrint(sexp, PrettyOptions{}))
+}
+
+func TestPrettyPrintDeep(t *testing.T) {
+	depth := 1200
+	sexp, err := ReadFully(strings.Repeat("(f x ", depth) + strings.Repeat(")", depth))
+	require.Nil(t, err)
+	printed := PrettyPrint(sexp, PrettyOptions{Width: 40})
+	lines := strings.Split(printed, "\n")
+	require.Equal(t, depth, len(lines))
+	require.Equal(t, "   (f x", lines[1])
+
+	// on one line, the layout is the same as Print's
+	for _, in := range []string{"`(a ~b ~@c (unquote @d) 'e)", `{:a [1 "b" #"c"] :d {}}`, "((f) () nil)"} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		require.Equal(t, Print(sexp), PrettyPrint(sexp, PrettyOptions{Width: 1000}), in)
+	}
+}
@@ -0,0 +1,433 @@
+package minsexp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Printer is implemented by host types that print themselves, e.g. as a tagged literal like #money "12.50 EUR".
+// Print uses PrintSexp in place of its own rendering of such values
+type Printer interface {
+	PrintSexp() string
+}
+
+// ErrCycle is returned by Fprint and TraverseLists for values that contain themselves, like a list that is its own
+// element
+var ErrCycle = errors.New("minsexp: cyclic structure")
+
+// cycleMarker is printed by Print in place of a value that contains itself
+const cycleMarker = "#<cycle>"
+
+// elision is printed in place of the elements and values exceeding the limits of PrintOptions
+const elision = "..."
+
+// PrintOptions limit how much of a value is printed, e.g. to log large values. Limits that are 0 are not enforced
+type PrintOptions struct {
+	// MaxDepth limits the nesting of lists, vectors and maps. Deeper ones are printed as ...
+	MaxDepth int
+	// MaxLength limits the number of elements of lists and vectors, and of entries of maps. The ones exceeding
+	// it are printed as a single ...
+	MaxLength int
+}
+
+// Print prints sexpI in sexp syntax. Besides sexps, it prints Go values like maps, slices, structs and numbers,
+// see Printer for host types that print themselves. Values that contain themselves are printed as #<cycle> where
+// they occur inside themselves. If printing fails, see Fprint, Print returns the error text
+func Print(sexpI interface{}) string {
+	return PrintWithOptions(sexpI, PrintOptions{})
+}
+
+// PrintWithOptions prints sexpI like Print does, eliding what exceeds the limits set in opts
+func PrintWithOptions(sexpI interface{}, opts PrintOptions) string {
+	var sb strings.Builder
+	p := newPrinter(&sb, opts)
+	p.markCycles = true
+	if err := p.print(sexpI); err != nil {
+		return err.Error()
+	}
+	return sb.String()
+}
+
+// Fprint prints x like Print does to w, buffering the output. It returns ErrCycle for cyclic structures,
+// errors of w, and errors raised by host printers, like a panicking Printer
+func Fprint(w io.Writer, x interface{}) error {
+	return FprintWithOptions(w, x, PrintOptions{})
+}
+
+// FprintWithOptions prints x like Fprint does, eliding what exceeds the limits set in opts
+func FprintWithOptions(w io.Writer, x interface{}, opts PrintOptions) error {
+	bw := bufio.NewWriter(w)
+	if err := newPrinter(bw, opts).print(x); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// printWriter is implemented by both strings.Builder and bufio.Writer
+type printWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+type printer struct {
+	w    printWriter
+	opts PrintOptions
+	// markCycles prints cycles as #<cycle>, instead of returning ErrCycle
+	markCycles bool
+	// visiting are the lists, maps and pointers being printed, to detect cycles
+	visiting map[visit]bool
+	// depth is the number of lists, vectors and maps being printed
+	depth int
+	// tags are the tags of StdTags by the type of the values they print, looked up once per printer
+	tags map[reflect.Type]string
+}
+
+// visit identifies a slice, map or pointer by the memory it refers to
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func newPrinter(w printWriter, opts PrintOptions) *printer {
+	return &printer{w: w, opts: opts}
+}
+
+func (p *printer) print(sexpI interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			err, ok = r.(error)
+			if !ok {
+				err = fmt.Errorf("minsexp: %v", r)
+			}
+		}
+	}()
+	return p.printSexp(sexpI)
+}
+
+func (p *printer) printSexp(sexpI interface{}) error {
+	switch sexp := sexpI.(type) {
+	case nil:
+		p.w.WriteString("nil")
+	case Printer:
+		// PrintSexp may use its receiver, so a nil pointer prints as nil, like other nil pointers
+		if v := reflect.ValueOf(sexp); v.Kind() == reflect.Ptr && v.IsNil() {
+			p.w.WriteString("nil")
+		} else {
+			p.w.WriteString(sexp.PrintSexp())
+		}
+	case []interface{}:
+		if len(sexp) == 2 {
+			if sym, ok := sexp[0].(Symbol); ok {
+				if prefix, ok := readerMacroPrefixes[sym]; ok {
+					// ~@x would be read as (unquote-splicing x)
+					if prefix != "~" || !isSymbolStartingWith(sexp[1], "@") {
+						p.w.WriteString(prefix)
+						return p.printSexp(sexp[1])
+					}
+				}
+			}
+		}
+		return p.printSeq(sexpI, '(', sexp, ')')
+	case Vector:
+		return p.printSeq(sexpI, '[', sexp, ']')
+	case Map:
+		if ok, err := p.enter(sexpI, true); !ok {
+			return err
+		}
+		defer p.leave(sexpI, true)
+		p.w.WriteByte('{')
+		for i, entry := range sexp {
+			if i > 0 {
+				p.w.WriteByte(' ')
+			}
+			if p.elide(i) {
+				break
+			}
+			if err := p.printSexp(entry.Key); err != nil {
+				return err
+			}
+			p.w.WriteByte(' ')
+			if err := p.printSexp(entry.Value); err != nil {
+				return err
+			}
+		}
+		p.w.WriteByte('}')
+	case string:
+		p.w.WriteString(printString(sexp))
+	case Keyword:
+		p.w.WriteByte(':')
+		p.w.WriteString(string(sexp))
+	case Symbol:
+		p.w.WriteString(string(sexp))
+	case decimal.Decimal:
+		p.w.WriteString(sexp.String())
+	case *regexp.Regexp:
+		p.w.WriteString(printRegex(sexp))
+	default:
+		if ok, err := p.printTagged(sexp); ok {
+			return err
+		}
+		if ok, err := p.printHost(sexp); ok {
+			return err
+		}
+		fmt.Fprintf(p.w, "%v", sexp)
+	}
+	return nil
+}
+
+// isSymbolStartingWith tells whether x is a symbol starting with prefix
+func isSymbolStartingWith(x interface{}, prefix string) bool {
+	sym, ok := x.(Symbol)
+	return ok && strings.HasPrefix(string(sym), prefix)
+}
+
+// printSeq prints the elements of the list or vector seq, separated by spaces and enclosed in open and close
+func (p *printer) printSeq(seq interface{}, open byte, elems []interface{}, close byte) error {
+	if ok, err := p.enter(seq, true); !ok {
+		return err
+	}
+	defer p.leave(seq, true)
+	p.w.WriteByte(open)
+	for i, elem := range elems {
+		if i > 0 {
+			p.w.WriteByte(' ')
+		}
+		if p.elide(i) {
+			break
+		}
+		if err := p.printSexp(elem); err != nil {
+			return err
+		}
+	}
+	p.w.WriteByte(close)
+	return nil
+}
+
+// enter records that x, a list, vector or map if nested is set, is being printed. It returns false if x is not to be
+// printed, after printing ... in place of x if it is nested deeper than MaxDepth, or #<cycle> if x is being printed
+// already. In the latter case, it returns ErrCycle unless markCycles is set
+func (p *printer) enter(x interface{}, nested bool) (bool, error) {
+	if max := p.opts.MaxDepth; nested && max > 0 && p.depth >= max {
+		p.w.WriteString(elision)
+		return false, nil
+	}
+	if v, ok := visitOf(x); ok {
+		if p.visiting[v] {
+			if !p.markCycles {
+				return false, ErrCycle
+			}
+			p.w.WriteString(cycleMarker)
+			return false, nil
+		}
+		if p.visiting == nil {
+			p.visiting = map[visit]bool{}
+		}
+		p.visiting[v] = true
+	}
+	if nested {
+		p.depth++
+	}
+	return true, nil
+}
+
+func (p *printer) leave(x interface{}, nested bool) {
+	if v, ok := visitOf(x); ok {
+		delete(p.visiting, v)
+	}
+	if nested {
+		p.depth--
+	}
+}
+
+// elide prints ... in place of the element at index i and the ones following it, if i exceeds MaxLength
+func (p *printer) elide(i int) bool {
+	if max := p.opts.MaxLength; max > 0 && i >= max {
+		p.w.WriteString(elision)
+		return true
+	}
+	return false
+}
+
+func visitOf(x interface{}) (visit, bool) {
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return visit{}, false
+		}
+		return visit{v.Pointer(), v.Type(), v.Len()}, true
+	case reflect.Map, reflect.Ptr:
+		if v.IsNil() {
+			return visit{}, false
+		}
+		return visit{v.Pointer(), v.Type(), 0}, true
+	}
+	return visit{}, false
+}
+
+// printHost prints Go values that are not sexps in sexp syntax: integers and floats as numbers, maps as maps with
+// their entries sorted by printed key, slices and arrays as vectors, and structs as maps of their exported fields,
+// tagged with the name of their type, like #minsexp.Span {:Start "1:1" :End "1:2"}. Pointers print as what they point to.
+// Errors and fmt.Stringers print as strings of their text, and structs without exported fields are left to fmt
+func (p *printer) printHost(value interface{}) (bool, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || !v.IsNil() {
+		switch value := value.(type) {
+		case error:
+			p.w.WriteString(printString(value.Error()))
+			return true, nil
+		case fmt.Stringer:
+			p.w.WriteString(printString(value.String()))
+			return true, nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		p.w.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.w.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.w.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		p.w.WriteString(printFloat(v.Float(), v.Kind() == reflect.Float32))
+	case reflect.String:
+		p.w.WriteString(printString(v.String()))
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			p.w.WriteString("nil")
+			return true, nil
+		}
+		if elem := v.Elem(); elem.Kind() == reflect.Struct && !hasExportedField(elem.Type()) {
+			return false, nil
+		}
+		if ok, err := p.enter(value, false); !ok {
+			return true, err
+		}
+		defer p.leave(value, false)
+		return true, p.printSexp(v.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if ok, err := p.enter(value, true); !ok {
+			return true, err
+		}
+		defer p.leave(value, true)
+		p.w.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				p.w.WriteByte(' ')
+			}
+			if p.elide(i) {
+				break
+			}
+			if err := p.printSexp(v.Index(i).Interface()); err != nil {
+				return true, err
+			}
+		}
+		p.w.WriteByte(']')
+	case reflect.Map:
+		if ok, err := p.enter(value, true); !ok {
+			return true, err
+		}
+		defer p.leave(value, true)
+		// the entries are sorted by their printed form, so they are printed to a buffer first
+		entries := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			var sb strings.Builder
+			entryPrinter := *p
+			entryPrinter.w = &sb
+			if err := entryPrinter.printSexp(key.Interface()); err != nil {
+				return true, err
+			}
+			sb.WriteByte(' ')
+			if err := entryPrinter.printSexp(v.MapIndex(key).Interface()); err != nil {
+				return true, err
+			}
+			entries = append(entries, sb.String())
+		}
+		sort.Strings(entries)
+		p.w.WriteByte('{')
+		for i, entry := range entries {
+			if i > 0 {
+				p.w.WriteByte(' ')
+			}
+			if p.elide(i) {
+				break
+			}
+			p.w.WriteString(entry)
+		}
+		p.w.WriteByte('}')
+	case reflect.Struct:
+		t := v.Type()
+		if !hasExportedField(t) {
+			return false, nil
+		}
+		if t.Name() != "" {
+			p.w.WriteByte('#')
+			p.w.WriteString(t.String())
+			p.w.WriteByte(' ')
+		}
+		if ok, err := p.enter(value, true); !ok {
+			return true, err
+		}
+		defer p.leave(value, true)
+		p.w.WriteByte('{')
+		fields := 0
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.PkgPath == "" {
+				if fields > 0 {
+					p.w.WriteByte(' ')
+				}
+				if p.elide(fields) {
+					break
+				}
+				fields++
+				p.w.WriteByte(':')
+				p.w.WriteString(field.Name)
+				p.w.WriteByte(' ')
+				if err := p.printSexp(v.Field(i).Interface()); err != nil {
+					return true, err
+				}
+			}
+		}
+		p.w.WriteByte('}')
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func hasExportedField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// printFloat prints f as a decimal number, like 0.1 rather than 1e-01. Infinities and NaN print as ##Inf, ##-Inf and
+// ##NaN, which are read as float64s
+func printFloat(f float64, is32 bool) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "##Inf"
+	case math.IsInf(f, -1):
+		return "##-Inf"
+	case math.IsNaN(f):
+		return "##NaN"
+	case is32:
+		return decimal.NewFromFloat32(float32(f)).String()
+	}
+	return decimal.NewFromFloat(f).String()
+}
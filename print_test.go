@@ -0,0 +1,188 @@
+package minsexp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type sku struct {
+	Code  string
+	Price decimal.Decimal
+	Tags  []string
+	Stock map[string]int
+	Next  *sku
+	note  string
+}
+
+type currency string
+
+type opaque struct {
+	id int
+}
+
+func (c currency) PrintSexp() string {
+	return "#currency " + printString(string(c))
+}
+
+type discount struct {
+	percent int
+}
+
+func (d *discount) PrintSexp() string {
+	return fmt.Sprintf("#discount %d", d.percent)
+}
+
+func TestPrintHostTypes(t *testing.T) {
+	for expected, value := range map[string]interface{}{
+		"nil":                          nil,
+		"true":                         true,
+		"-42":                          int8(-42),
+		"18446744073709551615":         uint64(math.MaxUint64),
+		"0.1":                          0.1,
+		"1000000000000000000000":       1e21,
+		"##-Inf":                       math.Inf(-1),
+		"##NaN":                        math.NaN(),
+		`["a" "b"]`:                    []string{"a", "b"},
+		"[1 2 3]":                      [3]int{1, 2, 3},
+		"[]":                           []int(nil),
+		`{"a" 1 "b" 2 "c" 3}`:          map[string]int{"c": 3, "a": 1, "b": 2},
+		"{:a [1] :b nil}":              map[Keyword]interface{}{Keyword("b"): nil, Keyword("a"): []int{1}},
+		"(a 1 nil)":                    []interface{}{Symbol("a"), 1, nil},
+		`(#currency "EUR" 2)`:          []interface{}{currency("EUR"), 2},
+		`#inst "2024-05-01T00:00:00Z"`: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		`#minsexp.sku {:Code "A-1" :Price 12.5 :Tags ["x"] :Stock {"berlin" 2 "tokyo" 0} :Next nil}`: &sku{
+			Code: "A-1", Price: decimal.New(125, -1), Tags: []string{"x"}, Stock: map[string]int{"tokyo": 0, "berlin": 2}, note: "hidden",
+		},
+		"{:A 1}": struct{ A int }{1},
+	} {
+		require.Equal(t, expected, Print(value))
+	}
+	require.Equal(t, "0.1", Print(float32(0.1)))
+	// the floats without decimal representation are read back as float64s
+	for _, f := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		read, err := ReadFully(Print(f))
+		require.Nil(t, err, Print(f))
+		require.Equal(t, Print(f), Print(read))
+		require.IsType(t, f, read)
+	}
+	for _, in := range []string{"##inf", "##", "(##Infinity)"} {
+		_, err := ReadFully(in)
+		require.Equal(t, UnexpectedChar, err.(*ParseError).Kind, in)
+	}
+	require.Equal(t, "(#discount 5 nil x)", Print([]interface{}{&discount{5}, (*discount)(nil), Symbol("x")}))
+
+	// errors and fmt.Stringers are printed as strings of their text, structs without exported fields using fmt
+	var nilInt *big.Int
+	for expected, value := range map[string]interface{}{
+		`"bad input"`:        errors.New("bad input"),
+		`"123"`:              big.NewInt(123),
+		`"1.5s"`:             1500 * time.Millisecond,
+		`["UnexpectedChar"]`: []ParseErrorKind{UnexpectedChar},
+		"{7}":                opaque{7},
+		"&{7}":               &opaque{7},
+		"nil":                nilInt,
+		`{:err "x" :id {7}}`: map[Keyword]interface{}{"err": errors.New("x"), "id": opaque{7}},
+		`#minsexp.Span {:Start "1:1" :End "1:2"}`: Span{Position{0, 1, 1, 0}, Position{1, 1, 2, 1}},
+	} {
+		require.Equal(t, expected, Print(value))
+	}
+
+	// printed values can be read back
+	sexp, err := ReadFully(Print(map[string]interface{}{"prices": []float64{1.5, 2}, "active": true}))
+	require.Nil(t, err)
+	result, err := Eval(StdEnv, nil, []interface{}{Symbol("get"), sexp, "prices"})
+	require.Nil(t, err)
+	require.True(t, equal(Vector{decimal.New(15, -1), decimal.New(2, 0)}, result))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+type panickingPrinter struct{}
+
+func (panickingPrinter) PrintSexp() string {
+	panic("cannot print")
+}
+
+func TestFprint(t *testing.T) {
+	var buf bytes.Buffer
+	sexp, _ := ReadFully(`(let a 1 [a "b" {:c 'd}])`)
+	require.Nil(t, Fprint(&buf, sexp))
+	require.Equal(t, Print(sexp), buf.String())
+
+	list := []interface{}{Symbol("a"), nil}
+	list[1] = list
+	vector := Vector{nil}
+	vector[0] = Map{{Keyword("v"), vector}}
+	node := &sku{Code: "A-1"}
+	node.Next = node
+	goMap := map[string]interface{}{}
+	goMap["self"] = goMap
+	for _, cyclic := range []interface{}{list, vector, node, goMap, []interface{}{goMap}} {
+		err := Fprint(&buf, cyclic)
+		require.True(t, errors.Is(err, ErrCycle), Print(cyclic))
+	}
+
+	// the same value may occur more than once, as long as it does not contain itself
+	shared := Vector{decimal.New(1, 0)}
+	require.Equal(t, "([1] [1])", Print([]interface{}{shared, shared}))
+
+	require.EqualError(t, Fprint(failingWriter{}, sexp), "disk full")
+	require.EqualError(t, Fprint(&buf, []interface{}{panickingPrinter{}}), "minsexp: cannot print")
+}
+
+func TestPrintCycles(t *testing.T) {
+	list := []interface{}{Symbol("a"), nil}
+	list[1] = list
+	vector := Vector{nil}
+	vector[0] = Map{{Keyword("v"), vector}}
+	node := &sku{Code: "A-1"}
+	node.Next = node
+	goMap := map[string]interface{}{}
+	goMap["self"] = goMap
+	for expected, cyclic := range map[string]interface{}{
+		"(a #<cycle>)":     list,
+		"[{:v #<cycle>}]":  vector,
+		"(b (a #<cycle>))": []interface{}{Symbol("b"), list},
+		`#minsexp.sku {:Code "A-1" :Price 0 :Tags [] :Stock {} :Next #<cycle>}`: node,
+		`{"self" #<cycle>}`: goMap,
+	} {
+		require.Equal(t, expected, Print(cyclic))
+	}
+	for _, cyclic := range []interface{}{list, vector, Map{{"k", Vector{list}}}} {
+		require.Equal(t, ErrCycle, TraverseLists(cyclic, func([]interface{}) error { return nil }))
+	}
+	require.Equal(t, "(a (a #<cycle>))", PrettyPrint([]interface{}{Symbol("a"), list}, PrettyOptions{Width: 4}))
+	require.Equal(t, "(a #<cycle>)", PrettyPrint(list, PrettyOptions{Width: 4}))
+}
+
+func TestPrintLimits(t *testing.T) {
+	sexp, _ := ReadFully(`(let a [1 2 3 4] (f {:a (g (h 1)) :b 2 :c 3} "long string"))`)
+	for expected, opts := range map[string]PrintOptions{
+		`(let a [1 2 3 4] (f {:a (g (h 1)) :b 2 :c 3} "long string"))`: {},
+		`(let a [1 2 3 ...] ...)`:                                      {MaxLength: 3},
+		`(let a [1 2 3 4] (f ... "long string"))`:                      {MaxDepth: 2},
+		`(let a [1 2 3 4] (f {:a ... :b 2 :c 3} "long string"))`:       {MaxDepth: 3, MaxLength: 4},
+	} {
+		require.Equal(t, expected, PrintWithOptions(sexp, opts))
+	}
+
+	require.Equal(t, `[1 2 ...]`, PrintWithOptions([]int{1, 2, 3}, PrintOptions{MaxLength: 2}))
+	require.Equal(t, `{"a" 1 ...}`, PrintWithOptions(map[string]int{"b": 2, "a": 1}, PrintOptions{MaxLength: 1}))
+	require.Equal(t, `#minsexp.sku {:Code "A-1" ...}`, PrintWithOptions(sku{Code: "A-1"}, PrintOptions{MaxLength: 1}))
+	require.Equal(t, `[[1 ...] ...]`, PrintWithOptions([][]int{{1, 2}, {3}}, PrintOptions{MaxDepth: 2, MaxLength: 1}))
+
+	var buf bytes.Buffer
+	require.Nil(t, FprintWithOptions(&buf, sexp, PrintOptions{MaxLength: 3}))
+	require.Equal(t, `(let a [1 2 3 ...] ...)`, buf.String())
+}
@@ -0,0 +1,132 @@
+package minsexp
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Quasiquote returns template, with (unquote x) (read from ~x) replaced by the value of x, and (unquote-splicing x)
+// (read from ~@x) replaced by the elements of the value of x, which has to be a list or a vector.
+// Unquotes inside nested quasiquotes are left alone, unless they are nested in as many unquotes.
+// This is what the built-in quasiquote special form (read from `template) evaluates to
+func Quasiquote(env map[string]interface{}, lexicalScope []map[string]interface{}, template interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = errors.WithStack(panicError(r))
+		}
+	}()
+	result, err = quasiquote(env, lexicalScope, template)
+	if evalErr, ok := err.(*evalError); ok {
+		return nil, evalErr.err
+	}
+	return result, err
+}
+
+// quasiquote is like Quasiquote, but returns errors as *evalError and passes on panics as *evalPanic, like eval
+func quasiquote(env map[string]interface{}, lexicalScope []map[string]interface{}, template interface{}) (interface{}, error) {
+	if isPrefixForm(template, "unquote-splicing") {
+		return nil, errors.New("unquote-splicing used outside of a list or vector")
+	}
+	return quasiquoteForm(env, lexicalScope, template, 1)
+}
+
+func quasiquoteForm(env map[string]interface{}, lexicalScope []map[string]interface{}, template interface{}, depth int) (interface{}, error) {
+	switch template := template.(type) {
+	case []interface{}:
+		if isPrefixForm(template, "unquote") {
+			if depth == 1 {
+				result, err := eval(env, lexicalScope, template[1])
+				if err != nil {
+					return nil, elemError(err, template, 1)
+				}
+				return result, nil
+			}
+			return quasiquotePrefixForm(env, lexicalScope, template, depth-1)
+		}
+		if isPrefixForm(template, "quasiquote") {
+			return quasiquotePrefixForm(env, lexicalScope, template, depth+1)
+		}
+		return quasiquoteElems(env, lexicalScope, template, depth)
+	case Vector:
+		result, err := quasiquoteElems(env, lexicalScope, template, depth)
+		if err != nil {
+			return nil, err
+		}
+		return Vector(result), nil
+	case Map:
+		result := make(Map, 0, len(template))
+		keys := newMapKeys(len(template))
+		for i, entry := range template {
+			key, err := quasiquoteForm(env, lexicalScope, entry.Key, depth)
+			if err != nil {
+				return nil, entryError(err, template, i, true)
+			}
+			if !keys.add(key) {
+				return nil, entryError(errors.New(fmt.Sprintf("duplicate key in map: %v", Print(key))), template, i, true)
+			}
+			value, err := quasiquoteForm(env, lexicalScope, entry.Value, depth)
+			if err != nil {
+				return nil, entryError(err, template, i, false)
+			}
+			result = append(result, MapEntry{key, value})
+		}
+		return result, nil
+	default:
+		return template, nil
+	}
+}
+
+// quasiquotePrefixForm expands the argument of a (sym x) form, which is left in place
+func quasiquotePrefixForm(env map[string]interface{}, lexicalScope []map[string]interface{}, template []interface{}, depth int) (interface{}, error) {
+	expanded, err := quasiquoteForm(env, lexicalScope, template[1], depth)
+	if err != nil {
+		return nil, elemError(err, template, 1)
+	}
+	return []interface{}{template[0], expanded}, nil
+}
+
+func quasiquoteElems(env map[string]interface{}, lexicalScope []map[string]interface{}, template []interface{}, depth int) ([]interface{}, error) {
+	if template == nil {
+		return nil, nil
+	}
+	result := make([]interface{}, 0, len(template))
+	for i, elem := range template {
+		if elemList, ok := elem.([]interface{}); ok && isPrefixForm(elemList, "unquote-splicing") {
+			if depth > 1 {
+				expanded, err := quasiquotePrefixForm(env, lexicalScope, elemList, depth-1)
+				if err != nil {
+					return nil, elemError(err, template, i)
+				}
+				result = append(result, expanded)
+				continue
+			}
+			spliced, err := eval(env, lexicalScope, elemList[1])
+			if err != nil {
+				return nil, elemError(elemError(err, elemList, 1), template, i)
+			}
+			switch spliced := spliced.(type) {
+			case []interface{}:
+				result = append(result, spliced...)
+			case Vector:
+				result = append(result, spliced...)
+			case nil:
+			default:
+				return nil, elemError(errors.New(fmt.Sprintf("unquote-splicing expects a list or a vector, but got: %v", Print(spliced))), template, i)
+			}
+			continue
+		}
+		expanded, err := quasiquoteForm(env, lexicalScope, elem, depth)
+		if err != nil {
+			return nil, elemError(err, template, i)
+		}
+		result = append(result, expanded)
+	}
+	return result, nil
+}
+
+// isPrefixForm returns whether form is a list (sym x)
+func isPrefixForm(form interface{}, sym Symbol) bool {
+	list, ok := form.([]interface{})
+	return ok && len(list) == 2 && list[0] == sym
+}
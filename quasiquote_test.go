@@ -0,0 +1,102 @@
+package minsexp
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQuasiquote(t *testing.T) {
+	lexicalScopes := []map[string]interface{}{{
+		"a":    decimal.New(1, 0),
+		"rest": []interface{}{Symbol("b"), Symbol("c")},
+		"v":    Vector{decimal.New(2, 0)},
+	}}
+	for inputForm, expectedOutput := range map[string]string{
+		"`a":                  "a",
+		"`(+ ~a ~@rest)":      "(+ 1 b c)",
+		"`(+ ~@v ~@rest ~a)":  "(+ 2 b c 1)",
+		"`(f ~(+ a 1))":       "(f 2)",
+		"`[~a ~@rest]":        "[1 b c]",
+		"`{~a [~@v]}":         "{1 [2]}",
+		"`(a ~@nil)":          "(a)",
+		"`(a (b ~a))":         "(a (b 1))",
+		"`(a `(b ~(c ~a)))":   "(a `(b ~(c 1)))",
+		"`(a `(b ~~a))":       "(a `(b ~1))",
+		"`(a `(b ~@(c ~@v)))": "(a `(b ~@(c 2)))",
+		"`()":                 "()",
+		"`'~a":                "'1",
+	} {
+		readSexp, idx, err := Read(inputForm, 0)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, idx, len(inputForm), inputForm)
+
+		printed := Print(readSexp)
+		require.Equal(t, inputForm, printed)
+
+		evalledSexp, err := Eval(StdEnv, lexicalScopes, readSexp)
+		require.Nil(t, err, inputForm)
+		require.Equal(t, expectedOutput, Print(evalledSexp), inputForm)
+	}
+}
+
+func TestQuasiquoteFromGo(t *testing.T) {
+	template, err := ReadFully("(discount ~rate ~@skus)")
+	require.Nil(t, err)
+	lexicalScopes := []map[string]interface{}{{
+		"rate": decimal.New(5, -2),
+		"skus": Vector{"A-1", "B-2"},
+	}}
+	expanded, err := Quasiquote(nil, lexicalScopes, template)
+	require.Nil(t, err)
+	require.Equal(t, `(discount 0.05 "A-1" "B-2")`, Print(expanded))
+}
+
+func TestQuasiquoteFromGoFails(t *testing.T) {
+	template, err := ReadFully("(a ~(fail))")
+	require.Nil(t, err)
+	failErr := errors.New("fail")
+	lexicalScopes := []map[string]interface{}{{
+		"fail": func(args []interface{}) (interface{}, error) {
+			return nil, failErr
+		},
+	}}
+	_, err = Quasiquote(nil, lexicalScopes, template)
+	require.Equal(t, failErr, err)
+}
+
+func TestQuasiquoteFromGoPanics(t *testing.T) {
+	template, err := ReadFully("(a ~(boom))")
+	require.Nil(t, err)
+	lexicalScopes := []map[string]interface{}{{
+		"boom": func(args []interface{}) (interface{}, error) {
+			panic("boom")
+		},
+	}}
+	_, err = Quasiquote(nil, lexicalScopes, template)
+	require.EqualError(t, err, "minsexp: boom")
+}
+
+func TestQuasiquoteReadForms(t *testing.T) {
+	testRead(t, "`a", []interface{}{Symbol("quasiquote"), Symbol("a")})
+	testRead(t, "~a", []interface{}{Symbol("unquote"), Symbol("a")})
+	testRead(t, "~@a", []interface{}{Symbol("unquote-splicing"), Symbol("a")})
+	testRead(t, "~ @a", []interface{}{Symbol("unquote"), Symbol("@a")})
+	require.Equal(t, "(unquote @a)", Print([]interface{}{Symbol("unquote"), Symbol("@a")}))
+}
+
+func TestQuasiquoteFails(t *testing.T) {
+	for _, inputForm := range []string{
+		"`~@a",
+		"`(~@a)",
+		"`(~@1)",
+		"`(~b)",
+		"`{~a 1 ~a 2}",
+		"(quasiquote)",
+		"(quasiquote a b)",
+	} {
+		_, err := ReadEval([]map[string]interface{}{{"a": decimal.New(1, 0)}}, inputForm)
+		require.NotNil(t, err, inputForm)
+	}
+}
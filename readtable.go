@@ -0,0 +1,120 @@
+package minsexp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ReaderMacro is called with the form following a dispatch tag or prefix character, and returns the form to read instead
+type ReaderMacro func(form interface{}) (interface{}, error)
+
+// Readtable extends the syntax of the reader with reader macros, see ReaderOptions.
+// It must not be modified while it is used for reading
+type Readtable struct {
+	dispatch map[string]ReaderMacro
+	prefixes map[rune]ReaderMacro
+}
+
+func NewReadtable() *Readtable {
+	return &Readtable{dispatch: make(map[string]ReaderMacro), prefixes: make(map[rune]ReaderMacro)}
+}
+
+// SetDispatch registers macro for #tag followed by a form, e.g. #date "2024-01-01" for the tag "date".
+// The tag may also be one of the characters ( [ { ", in which case the form starts with that character,
+// e.g. #{1 2} for the tag "{". Tags that are registered neither here nor in StdTags are read as part of a symbol, like #foo
+func (rt *Readtable) SetDispatch(tag string, macro ReaderMacro) {
+	if !isDispatchTag(tag) {
+		panic(fmt.Sprintf("minsexp: invalid dispatch tag %q", tag))
+	}
+	rt.dispatch[tag] = macro
+}
+
+// SetPrefix registers macro for c followed by a form, e.g. $price for '$'.
+// c must not be whitespace or a character with a meaning of its own: a delimiter, a digit, or one of " ' ` ~ : # , .
+func (rt *Readtable) SetPrefix(c rune, macro ReaderMacro) {
+	if c == utf8.RuneError || strings.ContainsRune("()[]{}\";'`~:#,.0123456789", c) || whitespaceLen(string(c), 0) > 0 {
+		panic(fmt.Sprintf("minsexp: invalid prefix character %q", c))
+	}
+	rt.prefixes[c] = macro
+}
+
+func isDispatchTag(tag string) bool {
+	if len(tag) == 1 && strings.Contains("([{\"", tag) {
+		return true
+	}
+	// #| and #_ start comments
+	return tag != "" && tag[0] != '|' && tag[0] != '_' && getNextNonSymbolChar(tag, 0) == len(tag)
+}
+
+// prefixMacro returns the macro registered for the character at idx, and the length of that character
+func (rt *Readtable) prefixMacro(s string, idx int) (ReaderMacro, int, bool) {
+	if len(rt.prefixes) == 0 {
+		return nil, 0, false
+	}
+	c, size := utf8.DecodeRuneInString(s[idx:])
+	macro, ok := rt.prefixes[c]
+	return macro, size, ok
+}
+
+// dispatchMacro returns the macro for the tag following the '#' at idx, as registered in the Readtable or in StdTags,
+// and the length of the '#' and the tag, unless the tag is one of the characters the following form starts with
+func (r *reader) dispatchMacro(idx int) (ReaderMacro, int, bool) {
+	s := r.s
+	if idx+1 >= len(s) {
+		return nil, 0, false
+	}
+	tag, prefixLen := s[idx+1:idx+2], 1
+	if !strings.Contains("([{\"", tag) {
+		endIdx := getNextNonSymbolChar(s, idx+1)
+		tag, prefixLen = s[idx+1:endIdx], endIdx-idx
+	}
+	if rt := r.opts.Readtable; rt != nil {
+		if macro, ok := rt.dispatch[tag]; ok {
+			return macro, prefixLen, true
+		}
+	}
+	if t, ok := StdTags[tag]; ok && t.Read != nil {
+		return t.Read, prefixLen, true
+	}
+	return nil, 0, false
+}
+
+// unknownTagError returns the error for a '#' at idx followed by a tag registered neither in the Readtable nor
+// in StdTags
+func (r *reader) unknownTagError(idx int) *ParseError {
+	s := r.s
+	endIdx := getNextNonSymbolChar(s, idx+1)
+	err := parseError(UnexpectedChar, idx, "unknown tag: "+s[idx:endIdx])
+	if endIdx == len(s) {
+		// the rest of the tag may still follow
+		err = err.expecting("tag", s, endIdx)
+	}
+	return err
+}
+
+// parseMacro reads the form following the prefix of prefixLen characters at startIdx, and passes it to macro
+func (r *reader) parseMacro(startIdx int, prefixLen int, macro ReaderMacro) (interface{}, int, error) {
+	defer r.leave()
+	if err := r.enter(startIdx); err != nil {
+		return nil, startIdx, err
+	}
+	formStartIdx, err := r.getNextNonWSP(startIdx + prefixLen)
+	if err != nil {
+		return r.skip(err, formStartIdx, formStartIdx)
+	}
+	form, idx, err := r.parseSexp(formStartIdx)
+	if err != nil {
+		return nil, idx, err
+	}
+	if _, ok := form.(skippedForm); ok {
+		return form, idx, nil
+	}
+	result, err := macro(form)
+	if err != nil {
+		parseErr := parseError(ReaderMacroFailed, startIdx, fmt.Sprintf("%v: %v", r.s[startIdx:startIdx+prefixLen], err))
+		parseErr.Err = err
+		return r.skip(parseErr, startIdx, idx)
+	}
+	return result, idx, nil
+}
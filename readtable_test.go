@@ -0,0 +1,129 @@
+package minsexp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func testReadtable() *Readtable {
+	rt := NewReadtable()
+	rt.SetDispatch("date", func(form interface{}) (interface{}, error) {
+		s, ok := form.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, but got %v", Print(form))
+		}
+		return time.Parse("2006-01-02", s)
+	})
+	// a set, as a vector without duplicates
+	rt.SetDispatch("[", func(form interface{}) (interface{}, error) {
+		var set Vector
+		var seen Map
+		for _, elem := range form.(Vector) {
+			if _, found := seen.Get(elem); !found {
+				seen = append(seen, MapEntry{elem, true})
+				set = append(set, elem)
+			}
+		}
+		return set, nil
+	})
+	// $price is short for (get obj :price)
+	rt.SetPrefix('$', func(form interface{}) (interface{}, error) {
+		sym, ok := form.(Symbol)
+		if !ok {
+			return nil, errors.New("expected a field name")
+		}
+		return []interface{}{Symbol("get"), Symbol("obj"), Keyword(sym)}, nil
+	})
+	return rt
+}
+
+func TestReadtable(t *testing.T) {
+	opts := ReaderOptions{Readtable: testReadtable()}
+
+	sexp, idx, err := ReadWithOptions("#date \"2024-01-01\"", 0, opts)
+	require.Nil(t, err)
+	require.Equal(t, 18, idx)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), sexp)
+
+	sexp, _, err = ReadWithOptions("[#[1 2 1] $price foo a$b]", 0, opts)
+	require.Nil(t, err)
+	require.Equal(t, "[[1 2] (get obj :price) foo a$b]", Print(sexp))
+
+	type item struct {
+		Price decimal.Decimal
+	}
+	sexp, _, err = ReadWithOptions("(* 2 $Price)", 0, opts)
+	require.Nil(t, err)
+	result, err := Eval(StdEnv, []map[string]interface{}{{"obj": &item{decimal.New(1250, -2)}}}, sexp)
+	require.Nil(t, err)
+	require.Equal(t, "25", result.(decimal.Decimal).String())
+
+	// without a readtable, prefix characters are part of symbols, and unknown tags are errors
+	sexps, err := ReadAll("$price")
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{Symbol("$price")}, sexps)
+	for in, expected := range map[string]*ParseError{
+		`(a #date "2024-01-01")`: {Kind: UnexpectedChar, Offset: 3, Line: 1, Column: 4, RuneOffset: 3},
+		`#mony "12.50 EUR"`:      {Kind: UnexpectedChar, Offset: 0, Line: 1, Column: 1, RuneOffset: 0},
+		`#(a)`:                   {Kind: UnexpectedChar, Offset: 0, Line: 1, Column: 1, RuneOffset: 0},
+	} {
+		_, err := ReadFully(in)
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, expected.Kind, parseErr.Kind, in)
+		require.Equal(t, expected.Position(), parseErr.Position(), in)
+		require.False(t, parseErr.IsIncomplete(), in)
+	}
+	_, err = ReadFully("(a #da")
+	require.True(t, err.(*ParseError).IsIncomplete())
+}
+
+func TestReadtableErrors(t *testing.T) {
+	opts := ReaderOptions{Readtable: testReadtable()}
+
+	_, _, err := ReadWithOptions("(a\n #date 2024)", 0, opts)
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, ReaderMacroFailed, parseErr.Kind)
+	require.Equal(t, Position{4, 2, 2, 4}, parseErr.Position())
+	require.Equal(t, "2:2: #date: expected a string, but got 2024\n #date 2024)\n ^", err.Error())
+
+	_, _, err = ReadWithOptions("#date \"01/01/2024\"", 0, opts)
+	var timeErr *time.ParseError
+	require.True(t, errors.As(err, &timeErr))
+
+	_, _, err = ReadWithOptions("(a $", 0, opts)
+	require.True(t, errors.As(err, &parseErr))
+	require.True(t, parseErr.IsIncomplete())
+
+	rt := NewReadtable()
+	for _, c := range []rune{'(', '"', ':', '#', '1', ' ', '　'} {
+		require.Panics(t, func() { rt.SetPrefix(c, nil) }, string(c))
+	}
+	for _, tag := range []string{"", "a b", "_x", "|x", ")"} {
+		require.Panics(t, func() { rt.SetDispatch(tag, nil) }, tag)
+	}
+}
+
+func TestReadtableDecoder(t *testing.T) {
+	d := NewDecoderWithOptions(iotest.OneByteReader(strings.NewReader("#date \"2024-01-01\" $price")), ReaderOptions{Readtable: testReadtable()})
+	var forms []interface{}
+	for {
+		form, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		forms = append(forms, form)
+	}
+	require.Equal(t, 2, len(forms))
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), forms[0])
+	require.Equal(t, "(get obj :price)", Print(forms[1]))
+}
@@ -0,0 +1,94 @@
+package minsexp
+
+// ReadAllRecovering reads all forms in sexpStr like ReadAllWithOptions does, but instead of stopping at the first
+// syntax error, it reports it and continues reading: after an invalid form, like 1x or "\q", at the next form,
+// after an unexpected closing paren, at the enclosing form it belongs to, if any, and at the end of the input, by
+// closing all open lists, vectors and maps.
+// It returns the forms read, leaving out invalid forms, and all ParseErrors in the order they were found.
+// LimitExceeded errors end reading
+func ReadAllRecovering(sexpStr string, opts ReaderOptions) ([]interface{}, []*ParseError) {
+	r := newReader(sexpStr, opts, startPosition)
+	r.recovering = true
+	var sexps []interface{}
+	idx := 0
+	for {
+		sexp, nextIdx, found, err := r.readNext(idx)
+		if err != nil {
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				parseErr = parseError(UnexpectedChar, nextIdx, err.Error())
+				parseErr.Err = err
+				r.locate(parseErr)
+			}
+			r.errs = append(r.errs, parseErr)
+			break
+		}
+		if !found {
+			break
+		}
+		if _, skipped := sexp.(skippedForm); !skipped {
+			sexps = append(sexps, sexp)
+		}
+		idx = nextIdx
+	}
+	for _, parseErr := range r.errs {
+		r.annotate(parseErr, parseErr.idx)
+	}
+	return sexps, r.errs
+}
+
+// skippedForm is read instead of invalid forms when recovering from errors
+type skippedForm struct{}
+
+// skip records err and returns a skippedForm along with resumeIdx, where to continue reading, when recovering from
+// errors. Otherwise, and for errors that cannot be recovered from, it returns err along with errIdx
+func (r *reader) skip(err error, errIdx int, resumeIdx int) (interface{}, int, error) {
+	parseErr, ok := err.(*ParseError)
+	if !r.recovering || !ok || parseErr.Kind == LimitExceeded {
+		return nil, errIdx, err
+	}
+	r.errs = append(r.errs, r.locate(parseErr).(*ParseError))
+	return skippedForm{}, resumeIdx, nil
+}
+
+// resyncIdx returns where to continue reading after the invalid form starting at startIdx
+func (r *reader) resyncIdx(startIdx int) int {
+	s := r.s
+	switch s[startIdx] {
+	case '"':
+		return skipString(s, startIdx)
+	case '#':
+		if startIdx+1 < len(s) && s[startIdx+1] == '"' {
+			return skipString(s, startIdx+1)
+		}
+	case ')', ']', '}', ',', '.':
+		return startIdx + 1
+	}
+	if idx := getNextNonSymbolChar(s, startIdx); idx > startIdx {
+		return idx
+	}
+	return startIdx + 1
+}
+
+// skipString returns the index after the string starting at startIdx, ignoring invalid escape sequences
+func skipString(s string, startIdx int) int {
+	for idx := startIdx + 1; idx < len(s); idx++ {
+		switch s[idx] {
+		case '\\':
+			idx++
+		case '"':
+			return idx + 1
+		}
+	}
+	return len(s)
+}
+
+// isEnclosingCloser tells whether c closes one of the lists, vectors or maps enclosing the one being read
+func (r *reader) isEnclosingCloser(c byte) bool {
+	for i := len(r.closers) - 2; i >= 0; i-- {
+		if r.closers[i] == c {
+			return true
+		}
+	}
+	return false
+}
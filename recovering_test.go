@@ -0,0 +1,63 @@
+package minsexp
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReadAllRecovering(t *testing.T) {
+	for in, expected := range map[string]struct {
+		forms  string
+		errors []ParseErrorKind
+		lines  []int
+	}{
+		"(a 1x b)\n(c \"\\q\" d)\n(e]\nf)\n(g": {
+			"(a b) (c d) (e f) (g)",
+			[]ParseErrorKind{BadNumber, UnexpectedChar, UnbalancedParen, Incomplete},
+			[]int{1, 2, 3, 5},
+		},
+		"[a (b c] d]":         {"[a (b c)] d", []ParseErrorKind{UnbalancedParen, UnbalancedParen}, []int{1, 1}},
+		"{:a 1 :a 2 :b}":      {"{:a 1}", []ParseErrorKind{UnexpectedChar, DuplicateKey}, []int{1, 1}},
+		"(a , b) 'c '1d . e":  {"(a b) 'c e", []ParseErrorKind{UnexpectedChar, BadNumber, UnexpectedChar}, []int{1, 1, 1}},
+		"#inst \"x\" 1 #| (a": {"1", []ParseErrorKind{ReaderMacroFailed, Incomplete}, []int{1, 1}},
+		"(a [b {:c \"d\n": {
+			"(a [b {}])",
+			[]ParseErrorKind{UnterminatedString, Incomplete, UnexpectedChar, Incomplete, Incomplete},
+			[]int{2, 2, 1, 2, 2},
+		},
+		"(a #_ 1x b) ; 1x\n(c) (d)": {"(a b) (c) (d)", []ParseErrorKind{BadNumber}, []int{1}},
+		"(a b) c":                   {"(a b) c", nil, nil},
+	} {
+		forms, errs := ReadAllRecovering(in, ReaderOptions{})
+		printed := ""
+		for _, form := range forms {
+			if printed != "" {
+				printed += " "
+			}
+			printed += Print(form)
+		}
+		require.Equal(t, expected.forms, printed, in)
+		require.Equal(t, len(expected.errors), len(errs), in)
+		for i, err := range errs {
+			require.Equal(t, expected.errors[i], err.Kind, in)
+			require.Equal(t, expected.lines[i], err.Line, in)
+		}
+	}
+}
+
+func TestReadAllRecoveringSnippets(t *testing.T) {
+	_, errs := ReadAllRecovering("(a 1x)\n(b]", ReaderOptions{File: "rules.sexp"})
+	require.Equal(t, 3, len(errs))
+	require.Equal(t, "rules.sexp:1:5: not a valid number, unexpected character 'x': 1x\n(a 1x)\n    ^", errs[0].Error())
+	require.Equal(t, "rules.sexp:2:3: expected ')' to close list starting at 2:1, but got ']'\n(b]\n  ^", errs[1].Error())
+	require.Equal(t, "rules.sexp:2:4: reached end of input parsing list starting at 2:1\n(b]\n   ^", errs[2].Error())
+}
+
+func TestReadAllRecoveringLimits(t *testing.T) {
+	forms, errs := ReadAllRecovering("(a 1x) (((b))) (c)", ReaderOptions{MaxDepth: 2})
+	require.Equal(t, 1, len(forms))
+	require.Equal(t, 2, len(errs))
+	require.Equal(t, BadNumber, errs[0].Kind)
+	require.Equal(t, LimitExceeded, errs[1].Kind)
+	require.Equal(t, 9, errs[1].Offset)
+}
@@ -0,0 +1,147 @@
+package minsexp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseRegex parses the regex literal starting at startIdx, like #"\d{3}-[A-Z]+", into a *regexp.Regexp.
+// Unlike in strings, backslashes are not escape characters, but part of the pattern. \" does not end the literal
+func (r *reader) parseRegex(startIdx int) (interface{}, int, error) {
+	s := r.s
+	for idx := startIdx + 2; idx < len(s); idx++ {
+		switch s[idx] {
+		case '\\':
+			idx++
+		case '"':
+			pattern := s[startIdx+2 : idx]
+			if max := r.opts.MaxStringLength; max > 0 && len(pattern) > max {
+				return nil, startIdx, parseError(LimitExceeded, startIdx, fmt.Sprintf("regex longer than MaxStringLength (%d)", max))
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				parseErr := parseError(BadRegex, startIdx, err.Error())
+				parseErr.Err = err
+				return nil, startIdx, parseErr
+			}
+			return re, idx + 1, nil
+		}
+	}
+	return nil, len(s), parseError(UnterminatedString, len(s), "regex not terminated by double quote").expecting("'\"'", s, len(s))
+}
+
+// printRegex prints re as a regex literal, escaping double quotes that are not escaped in the pattern
+func printRegex(re *regexp.Regexp) string {
+	pattern := re.String()
+	var sb strings.Builder
+	sb.WriteString(`#"`)
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			sb.WriteByte('\\')
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteByte(pattern[i])
+			}
+			continue
+		case '"':
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(pattern[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func regexArgs(fnName string, args []interface{}, argNames string) (*regexp.Regexp, string, error) {
+	usage := errors.New("Usage: (" + fnName + " <regex> <string>" + argNames + ")")
+	if len(args) != 2+len(strings.Fields(argNames)) {
+		return nil, "", usage
+	}
+	re, ok := args[0].(*regexp.Regexp)
+	if !ok {
+		return nil, "", usage
+	}
+	s, ok := args[1].(string)
+	if !ok {
+		return nil, "", usage
+	}
+	return re, s, nil
+}
+
+// match returns the match of re at loc in s: the matched string, or, if re has groups, a Vector of the matched string
+// followed by the groups, with nil for groups that did not participate in the match. It returns nil if loc is nil
+func match(s string, loc []int) interface{} {
+	if loc == nil {
+		return nil
+	}
+	if len(loc) == 2 {
+		return s[loc[0]:loc[1]]
+	}
+	groups := make(Vector, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return groups
+}
+
+// (re-find re s) returns the first match of re in s, or nil
+func reFindFn(args []interface{}) (interface{}, error) {
+	re, s, err := regexArgs("re-find", args, "")
+	if err != nil {
+		return nil, err
+	}
+	return match(s, re.FindStringSubmatchIndex(s)), nil
+}
+
+// (re-matches re s) returns the match of re if it matches all of s, or nil
+func reMatchesFn(args []interface{}) (interface{}, error) {
+	re, s, err := regexArgs("re-matches", args, "")
+	if err != nil {
+		return nil, err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc != nil && (loc[0] != 0 || loc[1] != len(s)) {
+		// the first match is not all of s, but another one might be, like ab for a|ab in "ab"
+		anchored, err := regexp.Compile(`\A(?:` + re.String() + `)\z`)
+		if err != nil {
+			return nil, err
+		}
+		loc = anchored.FindStringSubmatchIndex(s)
+	}
+	return match(s, loc), nil
+}
+
+// (re-seq re s) returns a Vector of all successive matches of re in s, or nil if there are none
+func reSeqFn(args []interface{}) (interface{}, error) {
+	re, s, err := regexArgs("re-seq", args, "")
+	if err != nil {
+		return nil, err
+	}
+	locs := re.FindAllStringSubmatchIndex(s, -1)
+	if locs == nil {
+		return nil, nil
+	}
+	matches := make(Vector, len(locs))
+	for i, loc := range locs {
+		matches[i] = match(s, loc)
+	}
+	return matches, nil
+}
+
+// (re-replace re s replacement) replaces all matches of re in s with replacement, in which $1 or ${name} denote groups
+func reReplaceFn(args []interface{}) (interface{}, error) {
+	re, s, err := regexArgs("re-replace", args, " <replacement>")
+	if err != nil {
+		return nil, err
+	}
+	replacement, ok := args[2].(string)
+	if !ok {
+		return nil, errors.New("Usage: (re-replace <regex> <string> <replacement>)")
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
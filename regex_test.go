@@ -0,0 +1,94 @@
+package minsexp
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"regexp/syntax"
+	"runtime"
+	"testing"
+)
+
+func TestRegexLiteral(t *testing.T) {
+	for in, pattern := range map[string]string{
+		`#"\d{3}-[A-Z]+"`:  `\d{3}-[A-Z]+`,
+		`#"a\"b"`:          `a\"b`,
+		`#"\\"`:            `\\`,
+		`#""`:              ``,
+		`#"(?i)größe\s+"`:  `(?i)größe\s+`,
+		`[#"a" #"b\.c" 1]`: ``,
+	} {
+		sexp, err := ReadFully(in)
+		require.Nil(t, err, in)
+		if re, ok := sexp.(*regexp.Regexp); ok {
+			require.Equal(t, pattern, re.String(), in)
+		}
+		require.Equal(t, in, Print(sexp), in)
+	}
+
+	hostRe := regexp.MustCompile(`a"b`)
+	require.Equal(t, `#"a\"b"`, Print(hostRe))
+	readRe, err := ReadFully(Print(hostRe))
+	require.Nil(t, err)
+	require.True(t, equal(hostRe, readRe))
+	require.False(t, equal(hostRe, regexp.MustCompile(`a\\"b`)))
+
+	// read regexes are left to the host, which may set a finalizer of its own
+	runtime.SetFinalizer(readRe, func(*regexp.Regexp) {})
+
+	for in, kind := range map[string]ParseErrorKind{
+		`(a #"[a-")`:  BadRegex,
+		`(a #"x\"`:    UnterminatedString,
+		`(a #"abcd")`: LimitExceeded,
+	} {
+		_, _, err := ReadWithOptions(in, 0, ReaderOptions{MaxStringLength: 3})
+		parseErr, ok := err.(*ParseError)
+		require.True(t, ok, in)
+		require.Equal(t, kind, parseErr.Kind, in)
+	}
+	_, err = ReadFully(`(a #"[a-")`)
+	require.Equal(t, 3, err.(*ParseError).Offset)
+	var syntaxErr *syntax.Error
+	require.True(t, errors.As(err, &syntaxErr))
+
+	forms, errs := ReadAllRecovering(`(a #"(b" #"\"(" c)`, ReaderOptions{})
+	require.Equal(t, 1, len(forms))
+	require.Equal(t, "(a c)", Print(forms[0]))
+	require.Equal(t, 2, len(errs))
+
+	src := `(re-find #"[0-9]+" "SKU-123")`
+	cst, err := ReadCST(src, ReaderOptions{})
+	require.Nil(t, err)
+	require.Equal(t, src, cst.String())
+	require.Equal(t, `#"[0-9]+"`, cst.Nodes[0].Children[1].Text)
+}
+
+func TestRegexFunctions(t *testing.T) {
+	for in, expected := range map[string]interface{}{
+		`(re-find #"\d+" "SKU-123-45")`:                     "123",
+		`(re-find #"(\w+)-(\d+)" "SKU-123")`:                Vector{"SKU-123", "SKU", "123"},
+		`(re-find #"(a)|(b)" "b")`:                          Vector{"b", nil, "b"},
+		`(re-find #"\d+" "SKU")`:                            nil,
+		`(re-matches #"a|ab" "ab")`:                         "ab",
+		`(re-matches #"\d+" "SKU-123")`:                     nil,
+		`(re-matches #"a|ab" "xab")`:                        nil,
+		`(re-matches #"(a|ab)(c|bcd)(d*)" "abcd")`:          Vector{"abcd", "a", "bcd", ""},
+		`(re-matches #"(?i)sku-(\d+)" "SKU-123")`:           Vector{"SKU-123", "123"},
+		`(re-seq #"\d+" "1 22 333")`:                        Vector{"1", "22", "333"},
+		`(re-seq #"(\d)(\d)" "12 34")`:                      Vector{Vector{"12", "1", "2"}, Vector{"34", "3", "4"}},
+		`(re-seq #"\d" "abc")`:                              nil,
+		`(re-replace #"(\w+)-(\d+)" "SKU-123" "${2}_$1")`:   "123_SKU",
+		`(= #"a+" #"a+")`:                                   true,
+		`(if (re-matches #"[A-Z]{3}-\d{3}" "ABC-123") 1 0)`: decimal.New(1, 0),
+	} {
+		result, err := ReadEval(nil, in)
+		require.Nil(t, err, in)
+		require.True(t, equal(expected, result), in)
+	}
+
+	for _, in := range []string{`(re-find "a" "a")`, `(re-find #"a")`, `(re-seq #"a" 1)`, `(re-replace #"a" "a")`, `(re-replace #"a" "a" 1)`} {
+		_, err := ReadEval(nil, in)
+		require.NotNil(t, err, in)
+	}
+}
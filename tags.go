@@ -0,0 +1,108 @@
+package minsexp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Tag defines a tagged literal, like #inst "2024-05-01T00:00:00Z"
+type Tag struct {
+	// Read converts the form following the tag into a value
+	Read ReaderMacro
+	// Type, if not nil, is the type of the values returned by Read. Print prints values of this type
+	// as the tag followed by the form returned by Print
+	Type  reflect.Type
+	Print func(value interface{}) interface{}
+}
+
+var (
+	// StdTags are the tagged literals known to the reader, by tag. Tags registered in a Readtable take precedence.
+	// If several tags have the same Type, Print uses the first by name
+	StdTags = map[string]Tag{}
+)
+
+// the built-in tags are added in init, as reading them refers to Print, which refers to StdTags
+func init() {
+	StdTags["inst"] = Tag{readInst, reflect.TypeOf(time.Time{}), printInst}
+	StdTags["uuid"] = Tag{readUUID, reflect.TypeOf(UUID{}), printUUID}
+}
+
+// printTagged prints the tagged literal for values of a type registered in StdTags.
+// It returns false if the type of value is not registered
+func (p *printer) printTagged(value interface{}) (bool, error) {
+	if p.tags == nil {
+		p.tags = tagsByType()
+	}
+	tag, ok := p.tags[reflect.TypeOf(value)]
+	if !ok {
+		return false, nil
+	}
+	p.w.WriteByte('#')
+	p.w.WriteString(tag)
+	p.w.WriteByte(' ')
+	return true, p.printSexp(StdTags[tag].Print(value))
+}
+
+// tagsByType returns the tags in StdTags that values are printed with, by type.
+// If several tags are registered for the same type, the first by name is used
+func tagsByType() map[reflect.Type]string {
+	tags := make(map[reflect.Type]string, len(StdTags))
+	for tag, t := range StdTags {
+		if t.Type == nil || t.Print == nil {
+			continue
+		}
+		if other, ok := tags[t.Type]; !ok || tag < other {
+			tags[t.Type] = tag
+		}
+	}
+	return tags
+}
+
+// #inst reads RFC 3339 timestamps as time.Time
+func readInst(form interface{}) (interface{}, error) {
+	s, ok := form.(string)
+	if !ok {
+		return nil, errors.New("expected an RFC 3339 timestamp string, but got " + Print(form))
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func printInst(value interface{}) interface{} {
+	return value.(time.Time).Format(time.RFC3339Nano)
+}
+
+// UUID is read from #uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"
+type UUID [16]byte
+
+// ParseUUID parses the canonical 8-4-4-4-12 hex digit form of a UUID
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return uuid, fmt.Errorf("invalid UUID %q", s)
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(uuid[:], []byte(digits)); err != nil {
+		return uuid, fmt.Errorf("invalid UUID %q", s)
+	}
+	return uuid, nil
+}
+
+func (uuid UUID) String() string {
+	s := hex.EncodeToString(uuid[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func readUUID(form interface{}) (interface{}, error) {
+	s, ok := form.(string)
+	if !ok {
+		return nil, errors.New("expected a UUID string, but got " + Print(form))
+	}
+	return ParseUUID(s)
+}
+
+func printUUID(value interface{}) interface{} {
+	return value.(UUID).String()
+}
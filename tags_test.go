@@ -0,0 +1,94 @@
+package minsexp
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstAndUUID(t *testing.T) {
+	for inputForm, expected := range map[string]interface{}{
+		`#inst "2024-05-01T00:00:00Z"`:                         time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		`#inst "2024-05-01T12:30:15.5+09:00"`:                  time.Date(2024, 5, 1, 3, 30, 15, 500000000, time.UTC),
+		`#uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"`:         UUID{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6},
+		`[#uuid "00000000-0000-0000-0000-000000000000" :x]`:    Vector{UUID{}, Keyword("x")},
+		`{:since #inst "1970-01-01T00:00:00Z" :until "never"}`: Map{{Keyword("since"), time.Unix(0, 0)}, {Keyword("until"), "never"}},
+	} {
+		sexp, err := ReadFully(inputForm)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expected, sexp), inputForm)
+		require.Equal(t, inputForm, Print(sexp))
+
+		evalled, err := Eval(StdEnv, nil, sexp)
+		require.Nil(t, err, inputForm)
+		require.True(t, equal(expected, evalled), inputForm)
+	}
+
+	result, err := ReadEval(nil, `(= #inst "2024-05-01T09:00:00+09:00" #inst "2024-05-01T00:00:00Z")`)
+	require.Nil(t, err)
+	require.Equal(t, true, result)
+}
+
+func TestTagErrors(t *testing.T) {
+	for _, in := range []string{`#inst "2024-05-01"`, `#inst 2024`, `#uuid "f81d4fae-7dec-11d0-a765"`, `#uuid "g81d4fae-7dec-11d0-a765-00a0c91e6bf6"`} {
+		_, err := ReadFully(in)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), in)
+		require.Equal(t, ReaderMacroFailed, parseErr.Kind, in)
+		require.Equal(t, 0, parseErr.Offset, in)
+	}
+	_, err := ReadFully(`#inst "2024-05-01"`)
+	var timeErr *time.ParseError
+	require.True(t, errors.As(err, &timeErr))
+}
+
+type money struct {
+	Amount   decimal.Decimal
+	Currency string
+}
+
+func TestRegisteredTag(t *testing.T) {
+	StdTags["money"] = Tag{
+		Read: func(form interface{}) (interface{}, error) {
+			fields := strings.Fields(form.(string))
+			amount, err := decimal.NewFromString(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			return &money{amount, fields[1]}, nil
+		},
+		Type: reflect.TypeOf(&money{}),
+		Print: func(value interface{}) interface{} {
+			m := value.(*money)
+			return m.Amount.StringFixed(2) + " " + m.Currency
+		},
+	}
+	defer delete(StdTags, "money")
+
+	sexp, err := ReadFully(`(get #money "12.50 EUR" :Currency)`)
+	require.Nil(t, err)
+	require.Equal(t, `(get #money "12.50 EUR" :Currency)`, Print(sexp))
+	result, err := Eval(StdEnv, nil, sexp)
+	require.Nil(t, err)
+	require.Equal(t, "EUR", result)
+
+	// a Readtable takes precedence
+	rt := NewReadtable()
+	rt.SetDispatch("money", func(form interface{}) (interface{}, error) {
+		return form, nil
+	})
+	sexp, _, err = ReadWithOptions(`#money "12.50 EUR"`, 0, ReaderOptions{Readtable: rt})
+	require.Nil(t, err)
+	require.Equal(t, "12.50 EUR", sexp)
+
+	// of several tags registered for the same type, the first by name is printed
+	StdTags["price"] = StdTags["money"]
+	defer delete(StdTags, "price")
+	for i := 0; i < 10; i++ {
+		require.Equal(t, `#money "12.50 EUR"`, Print(&money{decimal.New(125, -1), "EUR"}))
+	}
+}
@@ -1,7 +1,20 @@
 package minsexp
 
-// will only forward errors from cb; will not generate any errors of its own
+// TraverseLists calls cb for every list in exprI, including exprI itself, in depth-first order, descending into lists,
+// vectors and maps. It forwards errors from cb, and returns ErrCycle if exprI contains itself
 func TraverseLists(exprI interface{}, cb func([]interface{}) error) error {
+	return traverseLists(exprI, cb, map[visit]bool{})
+}
+
+// visiting are the lists, vectors and maps being traversed, to detect cycles
+func traverseLists(exprI interface{}, cb func([]interface{}) error, visiting map[visit]bool) error {
+	if v, ok := visitOf(exprI); ok {
+		if visiting[v] {
+			return ErrCycle
+		}
+		visiting[v] = true
+		defer delete(visiting, v)
+	}
 	switch expr := exprI.(type) {
 	case []interface{}:
 		e := cb(expr)
@@ -9,7 +22,25 @@ func TraverseLists(exprI interface{}, cb func([]interface{}) error) error {
 			return e
 		}
 		for _, v := range expr {
-			e := TraverseLists(v, cb)
+			e := traverseLists(v, cb, visiting)
+			if e != nil {
+				return e
+			}
+		}
+	case Vector:
+		for _, v := range expr {
+			e := traverseLists(v, cb, visiting)
+			if e != nil {
+				return e
+			}
+		}
+	case Map:
+		for _, entry := range expr {
+			e := traverseLists(entry.Key, cb, visiting)
+			if e != nil {
+				return e
+			}
+			e = traverseLists(entry.Value, cb, visiting)
 			if e != nil {
 				return e
 			}
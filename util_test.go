@@ -21,3 +21,15 @@ func TestTraverseLists(t *testing.T) {
 	require.Nil(t, e)
 	require.Equal(t, expectedListCounts, actualListCounts)
 }
+
+func TestTraverseListsCycle(t *testing.T) {
+	list := []interface{}{Symbol("a"), nil}
+	list[1] = Vector{list}
+	var visited int
+	e := TraverseLists(list, func([]interface{}) error {
+		visited++
+		return nil
+	})
+	require.Equal(t, ErrCycle, e)
+	require.Equal(t, 1, visited)
+}